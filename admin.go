@@ -0,0 +1,309 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	//DefaultAdminPageSize is the default number of elements in a page
+	DefaultAdminPageSize int = 100
+	//MaxAdminPageSize is the most elements in a page
+	MaxAdminPageSize int = 1000
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//AdminConfig is the config of the admin handler
+type AdminConfig struct {
+	ReadOnly bool //refuse anything that changes a queue
+}
+
+//---------------------------------------------------------------------------------------------------
+// Admin
+//---------------------------------------------------------------------------------------------------
+
+//NewAdminHandler returns a handler to look inside (and operate) the queues in a registry
+//Paths are relative to where it is mounted (use http.StripPrefix) and every response is JSON:
+//
+//	GET    /                          queues with their stats
+//	GET    /{name}                    stats of a queue
+//	GET    /{name}/elements           a page of elements in memory in queue order (?offset=&limit=)
+//	DELETE /{name}/elements/{handle}  remove a single element by handle
+//	POST   /{name}/flush              flush
+//	POST   /{name}/resize?size=       flush and resize
+//	POST   /{name}/pause              pause
+//	POST   /{name}/resume             resume
+//
+//An operation a queue does not support answers 501 and, read only, anything but GET answers 403.
+//Pages cover what is in memory only: total is the whole length (disk included) and memory what
+//can be paged through. Each element has the handle to remove it by.
+func NewAdminHandler(registry *Registry, config AdminConfig) http.Handler {
+	return &admin{registry: registry, config: config}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//admin is the admin handler
+type admin struct {
+	registry *Registry
+	config   AdminConfig
+}
+
+//adminQueue is a queue with its stats
+type adminQueue struct {
+	Name  string      `json:"name"`
+	Stats expvarStats `json:"stats"`
+}
+
+//adminPage is a page of elements
+type adminPage struct {
+	Offset   int            `json:"offset"`
+	Limit    int            `json:"limit"`
+	Total    int            `json:"total"`  //whole length (disk included)
+	Memory   int            `json:"memory"` //elements in memory (what can be paged)
+	Elements []adminElement `json:"elements"`
+}
+
+//adminElement is a single element
+type adminElement struct {
+	Position *int            `json:"position,omitempty"`
+	Priority int             `json:"priority"`
+	Handle   *uint64         `json:"handle,omitempty"`
+	Element  json.RawMessage `json:"element"`
+}
+
+//adminError is an error response
+type adminError struct {
+	Error string `json:"error"`
+}
+
+//ServeHTTP implements http.Handler
+func (a *admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	//List
+	if parts[0] == "" {
+		if !a.allow(w, r, http.MethodGet) {
+			return
+		}
+		queues := []adminQueue{}
+		for _, name := range a.registry.Names() {
+			if q, ok := a.registry.Get(name); ok {
+				queues = append(queues, adminQueue{Name: name, Stats: newExpvarStats(q.Stats())})
+			}
+		}
+		writeJSON(w, http.StatusOK, queues)
+		return
+	}
+	//A single queue
+	name := parts[0]
+	q, ok := a.registry.Get(name)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, adminError{fmt.Sprintf("no queue %q", name)})
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		if a.allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, adminQueue{Name: name, Stats: newExpvarStats(q.Stats())})
+		}
+	case len(parts) == 2 && parts[1] == "elements":
+		if a.allow(w, r, http.MethodGet) {
+			a.page(w, r, q)
+		}
+	case len(parts) == 3 && parts[1] == "elements":
+		if a.allow(w, r, http.MethodDelete) {
+			a.remove(w, q, parts[2])
+		}
+	case len(parts) == 2 && parts[1] == "flush":
+		if a.allow(w, r, http.MethodPost) {
+			a.flush(w, q)
+		}
+	case len(parts) == 2 && parts[1] == "resize":
+		if a.allow(w, r, http.MethodPost) {
+			a.resize(w, r, q)
+		}
+	case len(parts) == 2 && (parts[1] == "pause" || parts[1] == "resume"):
+		if a.allow(w, r, http.MethodPost) {
+			a.pause(w, q, parts[1] == "pause")
+		}
+	default:
+		writeJSON(w, http.StatusNotFound, adminError{"not found"})
+	}
+}
+
+//allow checks the method (and read only) and answers if not allowed
+func (a *admin) allow(w http.ResponseWriter, r *http.Request, method string) (ok bool) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJSON(w, http.StatusMethodNotAllowed, adminError{"method not allowed"})
+		return
+	}
+	if a.config.ReadOnly && method != http.MethodGet {
+		writeJSON(w, http.StatusForbidden, adminError{"read only"})
+		return
+	}
+	ok = true
+	return
+}
+
+//page answers a page of elements
+func (a *admin) page(w http.ResponseWriter, r *http.Request, q Statistics) {
+	peeker, ok := q.(PeekPriority)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, adminError{"peek not supported"})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, adminError{"invalid offset"})
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultAdminPageSize)
+	if err != nil || limit <= 0 {
+		writeJSON(w, http.StatusBadRequest, adminError{"invalid limit"})
+		return
+	}
+	if limit > MaxAdminPageSize {
+		limit = MaxAdminPageSize
+	}
+	var elements []interface{}
+	var priorities []int
+	var handles []uint64
+	if hp, ok := q.(handlePeeker); ok {
+		elements, priorities, handles = hp.peekHandles()
+	} else {
+		elements, priorities, _ = peeker.PeekPriority()
+	}
+	page := adminPage{Offset: offset, Limit: limit, Total: len(elements), Memory: len(elements), Elements: []adminElement{}}
+	if info, ok := q.(Info); ok {
+		page.Total = info.GetLength()
+	}
+	for position := offset; position < len(elements) && position < offset+limit; position++ {
+		element := adminElement{
+			Position: new(int),
+			Priority: priorities[position],
+			Element:  marshalElement(elements[position]),
+		}
+		*element.Position = position
+		if handles != nil {
+			element.Handle = &handles[position]
+		}
+		page.Elements = append(page.Elements, element)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+//handlePeeker is a queue that can peek with the handles
+type handlePeeker interface {
+	peekHandles() (elements []interface{}, priorities []int, handles []uint64)
+}
+
+//peekHandles will peek at all elements in memory with priorities and handles
+func (q *queue) peekHandles() (elements []interface{}, priorities []int, handles []uint64) {
+	q.Lock()
+	defer q.Unlock()
+	q.age()
+	for _, container := range q.containers {
+		elements = append(elements, container.element)
+		priorities = append(priorities, container.priority)
+		handles = append(handles, container.seq)
+	}
+	return
+}
+
+//remove answers removing a single element
+func (a *admin) remove(w http.ResponseWriter, q Statistics, param string) {
+	remover, ok := q.(Remove)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, adminError{"remove not supported"})
+		return
+	}
+	handle, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, adminError{"invalid handle"})
+		return
+	}
+	element, priority, found := remover.Remove(handle)
+	if !found {
+		writeJSON(w, http.StatusNotFound, adminError{fmt.Sprintf("no element %d", handle)})
+		return
+	}
+	writeJSON(w, http.StatusOK, adminElement{Priority: priority, Handle: &handle, Element: marshalElement(element)})
+}
+
+//flush answers flushing
+func (a *admin) flush(w http.ResponseWriter, q Statistics) {
+	flusher, ok := q.(Flush)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, adminError{"flush not supported"})
+		return
+	}
+	elements, _ := flusher.Flush()
+	writeJSON(w, http.StatusOK, map[string]int{"flushed": len(elements)})
+}
+
+//resize answers resizing
+func (a *admin) resize(w http.ResponseWriter, r *http.Request, q Statistics) {
+	owner, ok := q.(Owner)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, adminError{"resize not supported"})
+		return
+	}
+	size, err := queryInt(r, "size", 0)
+	if err != nil || size <= 0 {
+		writeJSON(w, http.StatusBadRequest, adminError{"invalid size"})
+		return
+	}
+	elements, _ := owner.Resize(size)
+	writeJSON(w, http.StatusOK, map[string]int{"size": size, "flushed": len(elements)})
+}
+
+//pause answers pausing and resuming
+func (a *admin) pause(w http.ResponseWriter, q Statistics, pause bool) {
+	p, ok := q.(Pause)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, adminError{"pause not supported"})
+		return
+	}
+	if pause {
+		p.Pause()
+	} else {
+		p.Resume()
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": pause})
+}
+
+//queryInt returns an int query parameter (or a default if missing)
+func queryInt(r *http.Request, key string, def int) (value int, err error) {
+	param := r.URL.Query().Get(key)
+	if param == "" {
+		value = def
+		return
+	}
+	value, err = strconv.Atoi(param)
+	return
+}
+
+//marshalElement returns the JSON of an element (its %v string if it has none)
+func marshalElement(element interface{}) (raw json.RawMessage) {
+	raw, err := json.Marshal(element)
+	if err != nil {
+		raw, _ = json.Marshal(fmt.Sprintf("%v", element))
+	}
+	return
+}
+
+//writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
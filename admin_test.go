@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Admin
+//---------------------------------------------------------------------------------------------------
+
+//TestAdminHandler will test the admin endpoints
+func TestAdminHandler(t *testing.T) {
+	const name string = "AdminHandler"
+	cases := map[string]struct {
+		iReadOnly bool
+		iSpill    bool //only two held in memory
+		iMethod   string
+		iPath     string
+		oStatus   int
+		oBody     string
+		oLength   int
+	}{
+		"List": {
+			iMethod: http.MethodGet,
+			iPath:   "/",
+			oStatus: http.StatusOK,
+			oLength: 3,
+		},
+		"Stats": {
+			iMethod: http.MethodGet,
+			iPath:   "/jobs",
+			oStatus: http.StatusOK,
+			oLength: 3,
+		},
+		"Unknown_Queue": {
+			iMethod: http.MethodGet,
+			iPath:   "/nope",
+			oStatus: http.StatusNotFound,
+			oBody:   `{"error":"no queue \"nope\""}`,
+			oLength: 3,
+		},
+		"Page": {
+			iMethod: http.MethodGet,
+			iPath:   "/jobs/elements?offset=1&limit=1",
+			oStatus: http.StatusOK,
+			oBody:   `{"offset":1,"limit":1,"total":3,"memory":3,"elements":[{"position":1,"priority":2,"handle":1,"element":"b"}]}`,
+			oLength: 3,
+		},
+		"Page_Past_End": {
+			iMethod: http.MethodGet,
+			iPath:   "/jobs/elements?offset=5",
+			oStatus: http.StatusOK,
+			oBody:   `{"offset":5,"limit":100,"total":3,"memory":3,"elements":[]}`,
+			oLength: 3,
+		},
+		"Page_Spilled": {
+			iSpill:  true,
+			iMethod: http.MethodGet,
+			iPath:   "/jobs/elements",
+			oStatus: http.StatusOK,
+			oBody:   `{"offset":0,"limit":100,"total":3,"memory":2,"elements":[{"position":0,"priority":3,"handle":0,"element":"a"},{"position":1,"priority":2,"handle":1,"element":"b"}]}`,
+			oLength: 3,
+		},
+		"Page_Invalid": {
+			iMethod: http.MethodGet,
+			iPath:   "/jobs/elements?limit=x",
+			oStatus: http.StatusBadRequest,
+			oLength: 3,
+		},
+		"Remove": {
+			iMethod: http.MethodDelete,
+			iPath:   "/jobs/elements/1",
+			oStatus: http.StatusOK,
+			oBody:   `{"priority":2,"handle":1,"element":"b"}`,
+			oLength: 2,
+		},
+		"Remove_Missing": {
+			iMethod: http.MethodDelete,
+			iPath:   "/jobs/elements/9",
+			oStatus: http.StatusNotFound,
+			oLength: 3,
+		},
+		"Flush": {
+			iMethod: http.MethodPost,
+			iPath:   "/jobs/flush",
+			oStatus: http.StatusOK,
+			oBody:   `{"flushed":3}`,
+		},
+		"Resize": {
+			iMethod: http.MethodPost,
+			iPath:   "/jobs/resize?size=8",
+			oStatus: http.StatusOK,
+			oBody:   `{"flushed":3,"size":8}`,
+		},
+		"Pause": {
+			iMethod: http.MethodPost,
+			iPath:   "/jobs/pause",
+			oStatus: http.StatusOK,
+			oBody:   `{"paused":true}`,
+			oLength: 3,
+		},
+		"Resume": {
+			iMethod: http.MethodPost,
+			iPath:   "/jobs/resume",
+			oStatus: http.StatusOK,
+			oBody:   `{"paused":false}`,
+			oLength: 3,
+		},
+		"Wrong_Method": {
+			iMethod: http.MethodGet,
+			iPath:   "/jobs/flush",
+			oStatus: http.StatusMethodNotAllowed,
+			oLength: 3,
+		},
+		"Read_Only": {
+			iReadOnly: true,
+			iMethod:   http.MethodPost,
+			iPath:     "/jobs/flush",
+			oStatus:   http.StatusForbidden,
+			oLength:   3,
+		},
+		"Read_Only_Get": {
+			iReadOnly: true,
+			iMethod:   http.MethodGet,
+			iPath:     "/jobs",
+			oStatus:   http.StatusOK,
+			oLength:   3,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(4, true)
+		if c.iSpill {
+			testQueue = NewQueue(2, true)
+			dir, cleanup := tempDir(t)
+			defer cleanup()
+			if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		testQueue.EnqueuePriority("a", 3)
+		testQueue.EnqueuePriority("b", 2)
+		testQueue.EnqueuePriority("c", 1)
+		registry := NewRegistry()
+		registry.Register("jobs", testQueue)
+		//Request
+		recorder := httptest.NewRecorder()
+		handler := NewAdminHandler(registry, AdminConfig{ReadOnly: c.iReadOnly})
+		handler.ServeHTTP(recorder, httptest.NewRequest(c.iMethod, c.iPath, nil))
+		//Assert
+		assert.Equal(t, c.oStatus, recorder.Code, fmt.Sprintf("%s :Status", msg))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"), fmt.Sprintf("%s :Content-Type", msg))
+		assert.True(t, json.Valid(recorder.Body.Bytes()), fmt.Sprintf("%s :JSON", msg))
+		if c.oBody != "" {
+			assert.JSONEq(t, c.oBody, recorder.Body.String(), fmt.Sprintf("%s :Body", msg))
+		}
+		assert.Equal(t, c.oLength, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+		testQueue.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"sort"
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//AgingConfig is the config of priority aging
+//An element's effective priority rises by Step every Interval it waits, up to Cap (an element
+//enqueued at or above Cap keeps its priority). Order uses the effective priority; the original
+//is what Peek and Dequeue report.
+type AgingConfig struct {
+	Step     int           //priority gained every Interval (0 turns aging off)
+	Interval time.Duration //time waited for each Step (0 turns aging off)
+	Cap      int           //highest effective priority
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Aging provides methods of keeping low priorities from starving
+type Aging interface {
+	//SetAging will set (or with a zero config clear) priority aging
+	SetAging(config AgingConfig)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Aging Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetAging will set (or with a zero config clear) priority aging
+//What is spilled to disk ages too, and is paged back in ahead of memory once it ranks higher.
+func (q *queue) SetAging(config AgingConfig) {
+	q.Lock()
+	defer q.Unlock()
+	q.aging = nil
+	if config.Step > 0 && config.Interval > 0 {
+		q.aging = &aging{config: config}
+	}
+	q.age()
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//aging is the state of priority aging
+type aging struct {
+	config AgingConfig
+	now    time.Time //when the effective priorities are taken
+}
+
+//effective returns the priority of a container once aged
+func (a *aging) effective(c *container) (priority int) {
+	priority = c.priority
+	if priority >= a.config.Cap {
+		return
+	}
+	steps := int64(a.now.Sub(c.enqueued) / a.config.Interval)
+	if steps <= 0 {
+		return
+	}
+	if gain := int64(a.config.Cap - priority); steps*int64(a.config.Step) < gain {
+		priority += int(steps) * a.config.Step
+		return
+	}
+	priority = a.config.Cap
+	return
+}
+
+//before reports if container a should be dequeued before container b once aged
+func (a *aging) before(x, y *container) bool {
+	if px, py := a.effective(x), a.effective(y); px != py {
+		return px > py
+	}
+	return x.seq < y.seq
+}
+
+//age will reorder memory and disk by effective priority if aging (locked)
+//Something on disk that now ranks above the tail of memory is swapped in.
+func (q *queue) age() {
+	switch {
+	case q.aging == nil:
+	case q.spill != nil:
+		q.rebalance()
+	default:
+		q.reorder()
+	}
+}
+
+//reorder will sort memory, and disk if aging (by effective priority if aging) (locked)
+func (q *queue) reorder() {
+	if q.aging == nil {
+		sort.Sort(q)
+		return
+	}
+	q.aging.now = time.Now()
+	sort.Sort(q)
+	if q.spill != nil {
+		q.spill.reorder()
+	}
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Aging
+//---------------------------------------------------------------------------------------------------
+
+//TestSetAging will test low priorities overtaking once aged
+func TestSetAging(t *testing.T) {
+	const name string = "SetAging"
+	cases := map[string]struct {
+		iConfig     AgingConfig
+		oElements   []interface{}
+		oPriorities []int
+	}{
+		"Off": {
+			oElements:   []interface{}{"high", "low"},
+			oPriorities: []int{2, 0},
+		},
+		"Overtake": {
+			iConfig:     AgingConfig{Step: 1, Interval: 10 * time.Millisecond, Cap: 10},
+			oElements:   []interface{}{"low", "high"},
+			oPriorities: []int{0, 2},
+		},
+		"Cap": {
+			iConfig:     AgingConfig{Step: 1, Interval: 10 * time.Millisecond, Cap: 1},
+			oElements:   []interface{}{"high", "low"},
+			oPriorities: []int{2, 0},
+		},
+		"Above_Cap": {
+			iConfig:     AgingConfig{Step: 5, Interval: 10 * time.Millisecond, Cap: -1},
+			oElements:   []interface{}{"high", "low"},
+			oPriorities: []int{2, 0},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(2, true)
+		testQueue.SetAging(c.iConfig)
+		testQueue.EnqueuePriority("low", 0)
+		time.Sleep(35 * time.Millisecond)
+		testQueue.EnqueuePriority("high", 2)
+		//Peek agrees with dequeue
+		head, _ := testQueue.PeekHead()
+		assert.Equal(t, c.oElements[0], head, fmt.Sprintf("%s :Peek", msg))
+		//Dequeue
+		var elements []interface{}
+		var priorities []int
+		for range c.oElements {
+			element, priority, _ := testQueue.DequeuePriority()
+			elements = append(elements, element)
+			priorities = append(priorities, priority)
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oPriorities, priorities, fmt.Sprintf("%s :Priorities", msg))
+		testQueue.Close()
+	}
+}
+
+//TestAgingSpill will test an aged element is kept in memory over a newer, lower one
+func TestAgingSpill(t *testing.T) {
+	const name string = "AgingSpill"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	testQueue := NewQueue(2, true)
+	defer testQueue.Close()
+	if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	testQueue.SetAging(AgingConfig{Step: 10, Interval: 10 * time.Millisecond, Cap: 100})
+	testQueue.EnqueuePriority("X", 0)
+	time.Sleep(40 * time.Millisecond)
+	testQueue.EnqueuePriority("Y", 5)
+	testQueue.EnqueuePriority("Z", 50)
+	testQueue.EnqueuePriority("W", 1)
+	//Memory first, then disk in its own order
+	elements, _ := testQueue.Flush()
+	assert.Equal(t, []interface{}{"Z", "X", "Y", "W"}, elements, fmt.Sprintf("%s :Elements", name))
+}
+
+//TestAgingSpilled will test an element aged on disk is swapped in ahead of newer, higher ones
+func TestAgingSpilled(t *testing.T) {
+	const name string = "AgingSpilled"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	testQueue := NewQueue(2, true)
+	defer testQueue.Close()
+	if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	testQueue.SetAging(AgingConfig{Step: 10, Interval: 10 * time.Millisecond, Cap: 100})
+	testQueue.EnqueuePriority("X", 0)
+	testQueue.EnqueuePriority("A", 90)
+	testQueue.EnqueuePriority("B", 90)
+	//All reach the cap, where X is the oldest (though on disk)
+	time.Sleep(120 * time.Millisecond)
+	var elements []interface{}
+	for {
+		element, underflow := testQueue.Dequeue()
+		if underflow {
+			break
+		}
+		elements = append(elements, element)
+	}
+	assert.Equal(t, []interface{}{"X", "A", "B"}, elements, fmt.Sprintf("%s :Elements", name))
+}
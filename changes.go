@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//ChangeKind is the kind of change to a queue
+type ChangeKind int
+
+const (
+	//Enqueued is an element enqueued
+	Enqueued ChangeKind = iota
+	//Dequeued is an element dequeued
+	Dequeued
+	//Overflowed is an element refused as the queue is full
+	Overflowed
+	//Evicted is an element written to disk for lack of room in memory
+	Evicted
+	//Flushed is an element removed by Flush or Resize
+	Flushed
+	//Resized is the queue resized
+	Resized
+	//Closed is the queue closed (the last change sent)
+	Closed
+	//Removed is an element removed by handle
+	Removed
+	//Paused is the queue paused
+	Paused
+	//Resumed is the queue resumed
+	Resumed
+	//Expired is an element dropped for missing its deadline
+	Expired
+	//Corrupted is a spilled element dropped as it could not be read back
+	Corrupted
+)
+
+//String implements Stringer
+func (k ChangeKind) String() string {
+	switch k {
+	case Enqueued:
+		return "enqueued"
+	case Dequeued:
+		return "dequeued"
+	case Overflowed:
+		return "overflowed"
+	case Evicted:
+		return "evicted"
+	case Flushed:
+		return "flushed"
+	case Resized:
+		return "resized"
+	case Closed:
+		return "closed"
+	case Removed:
+		return "removed"
+	case Paused:
+		return "paused"
+	case Resumed:
+		return "resumed"
+	case Expired:
+		return "expired"
+	case Corrupted:
+		return "corrupted"
+	}
+	return "unknown"
+}
+
+//Change is a single change to a queue
+type Change struct {
+	Kind     ChangeKind //what changed
+	Handle   uint64     //handle of the element (its sequence number, unset for queue wide changes)
+	Priority int        //priority of the element
+	Time     time.Time  //when it changed
+	Length   int        //length of the queue after the change
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Subscribe provides methods of following the changes to a queue
+type Subscribe interface {
+	//Subscribe returns a channel of changes with its own buffer and a func to unsubscribe
+	Subscribe(buffer int, blocking bool) (changes <-chan Change, unsubscribe func())
+}
+
+//---------------------------------------------------------------------------------------------------
+// Subscribe Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Subscribe returns a channel of changes with its own buffer and a func to unsubscribe
+//A lossy subscriber misses changes while its buffer is full. A blocking subscriber holds up the
+//queue until it has room, so it must keep reading (or unsubscribe). The channel is closed on
+//unsubscribe or after the Closed change.
+func (q *queue) Subscribe(buffer int, blocking bool) (changes <-chan Change, unsubscribe func()) {
+	q.Lock()
+	defer q.Unlock()
+	if buffer < 0 {
+		buffer = 0
+	}
+	sub := &subscription{
+		changes:  make(chan Change, buffer),
+		done:     make(chan struct{}),
+		blocking: blocking,
+	}
+	q.subscriptions = append(q.subscriptions, sub)
+	changes = sub.changes
+	unsubscribe = func() {
+		//Release a blocked send before taking the lock
+		if !sub.stop() {
+			return
+		}
+		q.Lock()
+		defer q.Unlock()
+		q.unsubscribe(sub)
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//subscription is a single subscriber
+type subscription struct {
+	changes  chan Change
+	done     chan struct{}
+	blocking bool
+	once     sync.Once
+}
+
+//stop will release the subscriber, reporting if it was not already
+func (s *subscription) stop() (ok bool) {
+	s.once.Do(func() {
+		close(s.done)
+		ok = true
+	})
+	return
+}
+
+//send will deliver a change
+func (s *subscription) send(change Change) {
+	if s.blocking {
+		select {
+		case s.changes <- change:
+		case <-s.done:
+		}
+		return
+	}
+	select {
+	case s.changes <- change:
+	default:
+	}
+}
+
+//unsubscribe will remove and close a subscription (locked)
+func (q *queue) unsubscribe(sub *subscription) {
+	for index, other := range q.subscriptions {
+		if other == sub {
+			q.subscriptions = append(q.subscriptions[:index], q.subscriptions[index+1:]...)
+			close(sub.changes)
+			return
+		}
+	}
+}
+
+//publish will log and send a change to every subscriber (locked)
+func (q *queue) publish(kind ChangeKind, c *container) {
+	if len(q.subscriptions) == 0 && q.logger == nil {
+		return
+	}
+	change := Change{Kind: kind, Time: time.Now(), Length: q.length()}
+	if c != nil {
+		change.Handle, change.Priority = c.seq, c.priority
+	}
+	q.log(change)
+	for _, sub := range q.subscriptions {
+		sub.send(change)
+	}
+}
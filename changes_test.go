@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Subscribe
+//---------------------------------------------------------------------------------------------------
+
+//TestSubscribe will test the stream of changes
+func TestSubscribe(t *testing.T) {
+	const name string = "Subscribe"
+	cases := map[string]struct {
+		iBuffer   int
+		iBlocking bool
+		oKinds    []ChangeKind
+		oHandles  []uint64
+		oLengths  []int
+	}{
+		"Lossy": {
+			iBuffer:  3,
+			oKinds:   []ChangeKind{Enqueued, Overflowed, Dequeued},
+			oHandles: []uint64{0, 1, 0},
+			oLengths: []int{1, 1, 0},
+		},
+		"Blocking": {
+			iBuffer:   0,
+			iBlocking: true,
+			oKinds:    []ChangeKind{Enqueued, Overflowed, Dequeued, Enqueued, Flushed, Resized, Closed},
+			oHandles:  []uint64{0, 1, 0, 2, 2, 0, 0},
+			oLengths:  []int{1, 1, 0, 1, 0, 0, 0},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(1, true)
+		changes, unsubscribe := testQueue.Subscribe(c.iBuffer, c.iBlocking)
+		defer unsubscribe()
+		//Read until closed
+		var received []Change
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for change := range changes {
+				received = append(received, change)
+			}
+		}()
+		//Change
+		testQueue.EnqueuePriority(1, 5)
+		testQueue.Enqueue(2)
+		testQueue.Dequeue()
+		testQueue.Enqueue(3)
+		testQueue.Flush()
+		testQueue.Resize(2)
+		testQueue.Close()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%s :not closed", msg)
+		}
+		//Lossy subscribers keep what fits
+		if !c.iBlocking {
+			received = received[:len(c.oKinds)]
+		}
+		//Assert
+		var kinds []ChangeKind
+		var handles []uint64
+		var lengths []int
+		for _, change := range received {
+			kinds = append(kinds, change.Kind)
+			handles = append(handles, change.Handle)
+			lengths = append(lengths, change.Length)
+		}
+		assert.Equal(t, c.oKinds, kinds, fmt.Sprintf("%s :Kinds", msg))
+		assert.Equal(t, c.oHandles, handles, fmt.Sprintf("%s :Handles", msg))
+		assert.Equal(t, c.oLengths, lengths, fmt.Sprintf("%s :Lengths", msg))
+		assert.Equal(t, 5, received[0].Priority, fmt.Sprintf("%s :Priority", msg))
+	}
+}
+
+//TestUnsubscribe will test a blocking subscriber letting go
+func TestUnsubscribe(t *testing.T) {
+	const name string = "Unsubscribe"
+	//Create Queue
+	testQueue := NewQueue(2, true)
+	defer testQueue.Close()
+	changes, unsubscribe := testQueue.Subscribe(0, true)
+	//Nobody reads so this blocks until unsubscribed
+	enqueued := make(chan struct{})
+	go func() {
+		defer close(enqueued)
+		testQueue.Enqueue(1)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	unsubscribe()
+	unsubscribe()
+	select {
+	case <-enqueued:
+	case <-time.After(time.Second):
+		t.Fatalf("%s :still blocked", name)
+	}
+	//Assert
+	_, open := <-changes
+	assert.False(t, open, fmt.Sprintf("%s :Open", name))
+	assert.Equal(t, 1, testQueue.GetLength(), fmt.Sprintf("%s :Length", name))
+}
@@ -0,0 +1,179 @@
+package queue
+
+import (
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//MissPolicy is what happens to an element that has missed its deadline
+type MissPolicy int
+
+const (
+	//MissDeliver delivers it in deadline order with the missed flag
+	MissDeliver MissPolicy = iota
+	//MissDrop drops it (counted as expired)
+	MissDrop
+	//MissDemote moves it behind every element that has not missed, delivered with the missed flag
+	MissDemote
+)
+
+//String implements Stringer
+func (p MissPolicy) String() string {
+	switch p {
+	case MissDeliver:
+		return "deliver"
+	case MissDrop:
+		return "drop"
+	case MissDemote:
+		return "demote"
+	}
+	return "unknown"
+}
+
+//DeadlineConfig is the config of earliest deadline first ordering
+type DeadlineConfig struct {
+	Enabled bool       //order by earliest deadline first
+	Missed  MissPolicy //what happens to an element that has missed its deadline
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Deadline provides methods of scheduling by deadline
+type Deadline interface {
+	//SetDeadlines will set (or with a zero config clear) earliest deadline first ordering
+	SetDeadlines(config DeadlineConfig)
+	//EnqueueDeadline will enqueue a single element with priority and a deadline
+	EnqueueDeadline(element interface{}, priority int, deadline time.Time) (overflow bool)
+	//DequeueDeadline will dequeue a single element with its deadline and if it was missed
+	DequeueDeadline() (element interface{}, priority int, deadline time.Time, missed bool, underflow bool)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Deadline Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetDeadlines will set (or with a zero config clear) earliest deadline first ordering
+//Elements with a deadline go first, earliest first, then those without by priority. Deadlines are
+//checked on dequeue. What is spilled to disk is kept in the same order.
+func (q *queue) SetDeadlines(config DeadlineConfig) {
+	q.Lock()
+	defer q.Unlock()
+	q.deadlines = nil
+	if config.Enabled {
+		q.deadlines = &config
+	}
+	if q.spill == nil {
+		q.reorder()
+		return
+	}
+	q.spill.reorder()
+	q.rebalance()
+}
+
+//EnqueueDeadline will enqueue a single element with priority and a deadline
+func (q *queue) EnqueueDeadline(element interface{}, priority int, deadline time.Time) (overflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Enqueue
+	overflow = q.enqueue(&container{
+		element:  element,
+		priority: priority,
+		deadline: deadline,
+	})
+	//Trigger signal
+	q.triggerSignal()
+	return
+}
+
+//DequeueDeadline will dequeue a single element with its deadline and if it was missed
+func (q *queue) DequeueDeadline() (element interface{}, priority int, deadline time.Time, missed bool, underflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Dequeue
+	var head *container
+	if underflow, element, priority, head = q.dequeue(); !underflow {
+		deadline, missed = head.deadline, head.missed
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//class returns where a container falls in deadline order (lower first)
+func (c *container) class(policy MissPolicy) int {
+	switch {
+	case c.deadline.IsZero():
+		return 1
+	case c.missed && policy == MissDemote:
+		return 2
+	}
+	return 0
+}
+
+//order reports if container x goes before container y by deadline, if that decides it
+func (d *DeadlineConfig) order(x, y *container) (before bool, decided bool) {
+	if cx, cy := x.class(d.Missed), y.class(d.Missed); cx != cy {
+		return cx < cy, true
+	} else if cx == 0 && !x.deadline.Equal(y.deadline) {
+		return x.deadline.Before(y.deadline), true
+	}
+	return
+}
+
+//ahead reports if container a goes before container b by deadline, if deadlines decide it
+func (q *queue) ahead(a, b *container) (before bool, decided bool) {
+	if q.deadlines != nil {
+		before, decided = q.deadlines.order(a, b)
+	}
+	return
+}
+
+//miss will mark a container missed if past its deadline, reporting if newly so (locked)
+func (q *queue) miss(c *container, now time.Time) (missed bool) {
+	if c.missed || c.deadline.IsZero() || !now.After(c.deadline) {
+		return
+	}
+	c.missed, missed = true, true
+	q.counters.missed++
+	return
+}
+
+//expire will apply the miss policy to what is in memory (locked)
+func (q *queue) expire() {
+	if q.deadlines == nil {
+		return
+	}
+	now := time.Now()
+	changed := false
+	kept := q.containers[:0]
+	for _, c := range q.containers {
+		if !q.miss(c, now) {
+			kept = append(kept, c)
+			continue
+		}
+		changed = true
+		if q.deadlines.Missed != MissDrop {
+			kept = append(kept, c)
+			continue
+		}
+		q.counters.expired++
+		q.publish(Expired, c)
+	}
+	for index := len(kept); index < len(q.containers); index++ {
+		q.containers[index] = nil //Come garbage collect
+	}
+	q.containers = kept
+	if !changed {
+		return
+	}
+	q.fill()
+	q.reorder()
+	q.watermark()
+}
@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Deadline
+//---------------------------------------------------------------------------------------------------
+
+//deadlineElement is an element with a deadline (from now, 0 for none) for the deadline tests
+type deadlineElement struct {
+	element  interface{}
+	priority int
+	deadline time.Duration
+}
+
+//TestSetDeadlines will test earliest deadline first and the miss policies
+func TestSetDeadlines(t *testing.T) {
+	const name string = "SetDeadlines"
+	cases := map[string]struct {
+		iConfig   DeadlineConfig
+		iSpill    bool //only one held in memory
+		iElements []deadlineElement
+		oElements []interface{}
+		oMissed   []bool
+		oStats    [2]uint64 //missed, expired
+	}{
+		"Off": {
+			iElements: []deadlineElement{{"a", 0, 3 * time.Second}, {"b", 0, time.Second}, {"c", 9, 0}},
+			oElements: []interface{}{"c", "a", "b"},
+			oMissed:   []bool{false, false, false},
+		},
+		"Earliest_First": {
+			iConfig:   DeadlineConfig{Enabled: true},
+			iElements: []deadlineElement{{"a", 0, 3 * time.Second}, {"b", 0, time.Second}, {"c", 9, 0}, {"d", 0, 2 * time.Second}},
+			oElements: []interface{}{"b", "d", "a", "c"},
+			oMissed:   []bool{false, false, false, false},
+		},
+		"Spill": {
+			iConfig:   DeadlineConfig{Enabled: true},
+			iSpill:    true,
+			iElements: []deadlineElement{{"a", 0, time.Second}, {"b", 5, 0}},
+			oElements: []interface{}{"a", "b"},
+			oMissed:   []bool{false, false},
+		},
+		"Spill_Several": {
+			iConfig:   DeadlineConfig{Enabled: true},
+			iSpill:    true,
+			iElements: []deadlineElement{{"m1", 0, time.Hour}, {"m2", 0, 2 * time.Hour}, {"x", 5, 0}, {"y", 0, 3 * time.Hour}},
+			oElements: []interface{}{"m1", "m2", "y", "x"},
+			oMissed:   []bool{false, false, false, false},
+		},
+		"Deliver": {
+			iConfig:   DeadlineConfig{Enabled: true, Missed: MissDeliver},
+			iElements: []deadlineElement{{"a", 0, time.Second}, {"b", 0, -time.Second}, {"c", 9, 0}},
+			oElements: []interface{}{"b", "a", "c"},
+			oMissed:   []bool{true, false, false},
+			oStats:    [2]uint64{1, 0},
+		},
+		"Drop": {
+			iConfig:   DeadlineConfig{Enabled: true, Missed: MissDrop},
+			iElements: []deadlineElement{{"a", 0, time.Second}, {"b", 0, -time.Second}, {"c", 9, 0}},
+			oElements: []interface{}{"a", "c"},
+			oMissed:   []bool{false, false},
+			oStats:    [2]uint64{1, 1},
+		},
+		"Demote": {
+			iConfig:   DeadlineConfig{Enabled: true, Missed: MissDemote},
+			iElements: []deadlineElement{{"a", 0, time.Second}, {"b", 0, -time.Second}, {"c", 9, 0}},
+			oElements: []interface{}{"a", "c", "b"},
+			oMissed:   []bool{false, false, true},
+			oStats:    [2]uint64{1, 0},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		size := len(c.iElements)
+		if c.iSpill {
+			size = 1
+		}
+		testQueue := NewQueue(size, true)
+		if c.iSpill {
+			dir, cleanup := tempDir(t)
+			defer cleanup()
+			if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		testQueue.SetDeadlines(c.iConfig)
+		now := time.Now()
+		for _, e := range c.iElements {
+			var deadline time.Time
+			if e.deadline != 0 {
+				deadline = now.Add(e.deadline)
+			}
+			testQueue.EnqueueDeadline(e.element, e.priority, deadline)
+		}
+		//Dequeue
+		var elements []interface{}
+		var missed []bool
+		for {
+			element, _, _, miss, underflow := testQueue.DequeueDeadline()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+			missed = append(missed, miss)
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oMissed, missed, fmt.Sprintf("%s :Missed", msg))
+		stats := testQueue.Stats()
+		assert.Equal(t, c.oStats, [2]uint64{stats.Missed, stats.Expired}, fmt.Sprintf("%s :Stats", msg))
+		testQueue.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"expvar"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Expvar
+//---------------------------------------------------------------------------------------------------
+
+//NewExpvar returns an expvar.Var rendering the live stats of a queue as JSON
+func NewExpvar(q Statistics) expvar.Var {
+	return expvar.Func(func() interface{} {
+		return newExpvarStats(q.Stats())
+	})
+}
+
+//PublishExpvar will publish the live stats of a queue under a name (shown in /debug/vars)
+//Note: Like expvar.Publish this panics if the name is already taken
+func PublishExpvar(name string, q Statistics) (v expvar.Var) {
+	v = NewExpvar(q)
+	expvar.Publish(name, v)
+	return
+}
+
+//expvarStats is the JSON form of Stats (durations in seconds)
+type expvarStats struct {
+	Size       int           `json:"size"`
+	Length     int           `json:"length"`
+	Spilled    int           `json:"spilled"`
+	Enqueued   uint64        `json:"enqueued"`
+	Dequeued   uint64        `json:"dequeued"`
+	Overflows  uint64        `json:"overflows"`
+	Underflows uint64        `json:"underflows"`
+	Flushed    uint64        `json:"flushed"`
+	Expired    uint64        `json:"expired"`
+	Missed     uint64        `json:"missed"`
+	Evicted    uint64        `json:"evicted"`
+	Corrupted  uint64        `json:"corrupted"`
+	Depth      map[int]int   `json:"depth"`
+	OldestAge  float64       `json:"oldest_age_seconds"`
+	Latency    expvarLatency `json:"latency"`
+}
+
+//expvarLatency is the JSON form of a Histogram
+type expvarLatency struct {
+	Bounds []float64 `json:"bounds_seconds"`
+	Counts []uint64  `json:"counts"`
+	Count  uint64    `json:"count"`
+	Sum    float64   `json:"sum_seconds"`
+}
+
+//newExpvarStats converts Stats to their JSON form
+func newExpvarStats(stats Stats) (e expvarStats) {
+	e = expvarStats{
+		Size:       stats.Size,
+		Length:     stats.Length,
+		Spilled:    stats.Spilled,
+		Enqueued:   stats.Enqueued,
+		Dequeued:   stats.Dequeued,
+		Overflows:  stats.Overflows,
+		Underflows: stats.Underflows,
+		Flushed:    stats.Flushed,
+		Expired:    stats.Expired,
+		Missed:     stats.Missed,
+		Evicted:    stats.Evicted,
+		Corrupted:  stats.Corrupted,
+		Depth:      stats.Depth,
+		OldestAge:  stats.OldestAge.Seconds(),
+		Latency: expvarLatency{
+			Counts: stats.Latency.Counts,
+			Count:  stats.Latency.Count,
+			Sum:    stats.Latency.Sum.Seconds(),
+		},
+	}
+	for _, bound := range stats.Latency.Bounds {
+		e.Latency.Bounds = append(e.Latency.Bounds, bound.Seconds())
+	}
+	return
+}
@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Expvar
+//---------------------------------------------------------------------------------------------------
+
+//expvarRuns makes the published name unique per run (expvar names can not be reused)
+var expvarRuns uint64
+
+//TestPublishExpvar will test publishing live stats
+func TestPublishExpvar(t *testing.T) {
+	const name string = "PublishExpvar"
+	//Create Queue
+	testQueue := NewQueue(2, true)
+	defer testQueue.Close()
+	published := fmt.Sprintf("go-queue-test-%d", atomic.AddUint64(&expvarRuns, 1))
+	PublishExpvar(published, testQueue)
+	//Change after publishing
+	testQueue.EnqueuePriority(1, 3)
+	testQueue.EnqueuePriority(2, 3)
+	testQueue.Enqueue(3)
+	testQueue.Dequeue()
+	//Read back
+	var stats struct {
+		Size      int            `json:"size"`
+		Length    int            `json:"length"`
+		Overflows uint64         `json:"overflows"`
+		Depth     map[string]int `json:"depth"`
+		Latency   struct {
+			Bounds []float64 `json:"bounds_seconds"`
+			Count  uint64    `json:"count"`
+		} `json:"latency"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(published).String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	//Assert
+	assert.Equal(t, 2, stats.Size, fmt.Sprintf("%s :Size", name))
+	assert.Equal(t, 1, stats.Length, fmt.Sprintf("%s :Length", name))
+	assert.Equal(t, uint64(1), stats.Overflows, fmt.Sprintf("%s :Overflows", name))
+	assert.Equal(t, map[string]int{"3": 1}, stats.Depth, fmt.Sprintf("%s :Depth", name))
+	assert.Equal(t, uint64(1), stats.Latency.Count, fmt.Sprintf("%s :Latency", name))
+	assert.Equal(t, 0.001, stats.Latency.Bounds[0], fmt.Sprintf("%s :Bounds", name))
+}
@@ -0,0 +1,266 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+const (
+	//DefaultFairQuantum is the default credit a weight of 1 earns each deficit round-robin turn
+	DefaultFairQuantum int = 1
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//FairMode is how a fair queue picks tenants
+type FairMode int
+
+const (
+	//WeightedRoundRobin takes up to weight elements from each tenant in turn
+	WeightedRoundRobin FairMode = iota
+	//DeficitRoundRobin takes up to weight × Quantum cost from each tenant in turn, carrying over
+	//what a tenant could not spend while it has elements
+	DeficitRoundRobin
+)
+
+//String returns the name of a mode
+func (m FairMode) String() string {
+	switch m {
+	case WeightedRoundRobin:
+		return "wrr"
+	case DeficitRoundRobin:
+		return "drr"
+	}
+	return "unknown"
+}
+
+//FairConfig is the config of a fair queue
+type FairConfig struct {
+	Mode    FairMode
+	Size    int            //size of each tenant's queue (0 is DefaultSize)
+	Sizes   map[string]int //size of given tenants (instead of Size)
+	Weights map[string]int //weight of given tenants (1 if missing)
+	Quantum int            //with DeficitRoundRobin, credit a weight of 1 earns a turn (0 is DefaultFairQuantum)
+	//Cost is the cost of an element with DeficitRoundRobin (nil is 1 each)
+	Cost func(element interface{}) int
+}
+
+//---------------------------------------------------------------------------------------------------
+// FairQueue
+//---------------------------------------------------------------------------------------------------
+
+//FairQueue is a queue keyed by tenant
+//Each tenant has its own bounded queue, so a noisy tenant overflows only itself, and dequeue
+//takes tenants in turn by weight. Priority is respected within a tenant.
+type FairQueue struct {
+	mutex   sync.Mutex
+	config  FairConfig
+	tenants map[string]*fairTenant
+	order   []string      //tenants in turn order
+	next    int           //tenant whose turn it is
+	turn    bool          //the tenant whose turn it is has had its credit
+	wake    chan struct{} //closed to wake waiters (nil if none)
+	closed  bool
+}
+
+//NewFairQueue returns a new fair queue (call Close)
+func NewFairQueue(config FairConfig) (f *FairQueue) {
+	if config.Quantum <= 0 {
+		config.Quantum = DefaultFairQuantum
+	}
+	f = &FairQueue{config: config, tenants: make(map[string]*fairTenant)}
+	return
+}
+
+//Enqueue will enqueue a single element for a tenant
+func (f *FairQueue) Enqueue(tenant string, element interface{}, priority int) (overflow bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		overflow = true
+		return
+	}
+	if overflow = f.get(tenant).queue.EnqueuePriority(element, priority); !overflow {
+		f.wakeup()
+	}
+	return
+}
+
+//Dequeue will dequeue a single element from the tenant whose turn it is
+func (f *FairQueue) Dequeue() (tenant string, element interface{}, priority int, underflow bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	tenant, element, priority, underflow = f.dequeue()
+	return
+}
+
+//DequeueWait will wait for and dequeue a single element
+//It waits until ctx is done (ctx.Err()) or the fair queue is closed (ErrClosed).
+func (f *FairQueue) DequeueWait(ctx context.Context) (tenant string, element interface{}, priority int, err error) {
+	for {
+		f.mutex.Lock()
+		if f.closed {
+			f.mutex.Unlock()
+			err = ErrClosed
+			return
+		}
+		var underflow bool
+		if tenant, element, priority, underflow = f.dequeue(); !underflow {
+			f.mutex.Unlock()
+			return
+		}
+		if f.wake == nil {
+			f.wake = make(chan struct{})
+		}
+		wake := f.wake
+		f.mutex.Unlock()
+		if err = wait(ctx, wake, 0); err != nil {
+			return
+		}
+	}
+}
+
+//GetLength will return the length across tenants
+func (f *FairQueue) GetLength() (len int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, t := range f.tenants {
+		len += t.queue.GetLength()
+	}
+	return
+}
+
+//GetTenants will return the tenants seen (sorted)
+func (f *FairQueue) GetTenants() (tenants []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	tenants = append(tenants, f.order...)
+	sort.Strings(tenants)
+	return
+}
+
+//TenantStats returns a snapshot of the statistics of a tenant
+func (f *FairQueue) TenantStats(tenant string) (stats Stats, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var t *fairTenant
+	if t, ok = f.tenants[tenant]; ok {
+		stats = t.queue.Stats()
+	}
+	return
+}
+
+//Close will close every tenant's queue
+func (f *FairQueue) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for _, t := range f.tenants {
+		t.queue.Close()
+	}
+	f.wakeup()
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//fairTenant is the queue and turn state of a single tenant
+type fairTenant struct {
+	queue  *queue
+	weight int
+	credit int //what is left to take this turn (carried over with DeficitRoundRobin)
+}
+
+//get returns a tenant, adding it at the end of the turns if new (locked)
+func (f *FairQueue) get(name string) (t *fairTenant) {
+	var ok bool
+	if t, ok = f.tenants[name]; ok {
+		return
+	}
+	size, ok := f.config.Sizes[name]
+	if !ok {
+		size = f.config.Size
+	}
+	weight, ok := f.config.Weights[name]
+	if !ok || weight < 1 {
+		weight = 1
+	}
+	t = &fairTenant{queue: NewQueue(size, true).(*queue), weight: weight}
+	f.tenants[name] = t
+	f.order = append(f.order, name)
+	return
+}
+
+//dequeue will dequeue from the tenant whose turn it is, moving on as turns are used up (locked)
+func (f *FairQueue) dequeue() (name string, element interface{}, priority int, underflow bool) {
+	underflow = true
+	if f.closed {
+		return
+	}
+	//A full pass of empty tenants is an underflow
+	for empties := 0; empties < len(f.order); {
+		name = f.order[f.next]
+		t := f.tenants[name]
+		head, _, _, empty := t.queue.PeekWithMeta()
+		if empty {
+			//An idle tenant does not bank credit
+			t.credit = 0
+			f.advance()
+			empties++
+			continue
+		}
+		empties = 0
+		//Start of the turn
+		if !f.turn {
+			f.turn = true
+			switch f.config.Mode {
+			case DeficitRoundRobin:
+				t.credit += t.weight * f.config.Quantum
+			default:
+				t.credit = t.weight
+			}
+		}
+		cost := f.cost(head)
+		if cost > t.credit {
+			f.advance()
+			continue
+		}
+		t.credit -= cost
+		element, priority, underflow = t.queue.DequeuePriority()
+		return
+	}
+	name = ""
+	return
+}
+
+//cost returns the cost of an element in the current mode
+func (f *FairQueue) cost(element interface{}) (cost int) {
+	cost = 1
+	if f.config.Mode == DeficitRoundRobin && f.config.Cost != nil {
+		if cost = f.config.Cost(element); cost < 0 {
+			cost = 0
+		}
+	}
+	return
+}
+
+//advance will end the current turn (locked)
+func (f *FairQueue) advance() {
+	f.next = (f.next + 1) % len(f.order)
+	f.turn = false
+}
+
+//wakeup will wake everything waiting on the fair queue (locked)
+func (f *FairQueue) wakeup() {
+	if f.wake != nil {
+		close(f.wake)
+		f.wake = nil
+	}
+}
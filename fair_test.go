@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// FairQueue
+//---------------------------------------------------------------------------------------------------
+
+//fairElement is an element of a tenant for the fair queue tests
+type fairElement struct {
+	tenant   string
+	element  interface{}
+	priority int
+}
+
+//TestFairQueue will test the turns taken between tenants
+func TestFairQueue(t *testing.T) {
+	const name string = "FairQueue"
+	cases := map[string]struct {
+		iConfig    FairConfig
+		iElements  []fairElement
+		oTenants   []string
+		oElements  []interface{}
+		oOverflows map[string]uint64
+	}{
+		"Round_Robin": {
+			iConfig: FairConfig{Size: 4},
+			iElements: []fairElement{
+				{"a", 0, 0}, {"a", 1, 0}, {"a", 2, 0}, {"b", 0, 0},
+			},
+			oTenants:  []string{"a", "b", "a", "a"},
+			oElements: []interface{}{0, 0, 1, 2},
+		},
+		"Weighted": {
+			iConfig: FairConfig{Size: 4, Weights: map[string]int{"a": 2}},
+			iElements: []fairElement{
+				{"a", 0, 0}, {"a", 1, 0}, {"a", 2, 0}, {"b", 0, 0}, {"b", 1, 0}, {"b", 2, 0},
+			},
+			oTenants:  []string{"a", "a", "b", "a", "b", "b"},
+			oElements: []interface{}{0, 1, 0, 2, 1, 2},
+		},
+		"Deficit": {
+			iConfig: FairConfig{Mode: DeficitRoundRobin, Size: 4, Quantum: 2, Cost: func(element interface{}) int {
+				return element.(int)
+			}},
+			iElements: []fairElement{
+				{"a", 2, 0}, {"a", 2, 0}, {"b", 1, 0}, {"b", 1, 0}, {"b", 1, 0}, {"b", 1, 0},
+			},
+			oTenants:  []string{"a", "b", "b", "a", "b", "b"},
+			oElements: []interface{}{2, 1, 1, 2, 1, 1},
+		},
+		"Deficit_Carried": {
+			iConfig: FairConfig{Mode: DeficitRoundRobin, Size: 4, Cost: func(element interface{}) int {
+				return element.(int)
+			}},
+			iElements: []fairElement{
+				{"a", 3, 0}, {"b", 1, 0}, {"b", 1, 0}, {"b", 1, 0}, {"b", 1, 0},
+			},
+			oTenants:  []string{"b", "b", "a", "b", "b"},
+			oElements: []interface{}{1, 1, 3, 1, 1},
+		},
+		"Priority": {
+			iConfig: FairConfig{Size: 4},
+			iElements: []fairElement{
+				{"a", "low", 0}, {"a", "high", 5}, {"b", "low", 0}, {"b", "high", 5},
+			},
+			oTenants:  []string{"a", "b", "a", "b"},
+			oElements: []interface{}{"high", "high", "low", "low"},
+		},
+		"Capacity": {
+			iConfig: FairConfig{Size: 1, Sizes: map[string]int{"b": 2}},
+			iElements: []fairElement{
+				{"a", 0, 0}, {"a", 1, 0}, {"a", 2, 0}, {"b", 0, 0}, {"b", 1, 0},
+			},
+			oTenants:   []string{"a", "b", "b"},
+			oElements:  []interface{}{0, 0, 1},
+			oOverflows: map[string]uint64{"a": 2, "b": 0},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create FairQueue
+		testQueue := NewFairQueue(c.iConfig)
+		for _, e := range c.iElements {
+			testQueue.Enqueue(e.tenant, e.element, e.priority)
+		}
+		//Dequeue
+		var tenants []string
+		var elements []interface{}
+		for {
+			tenant, element, _, underflow := testQueue.Dequeue()
+			if underflow {
+				break
+			}
+			tenants = append(tenants, tenant)
+			elements = append(elements, element)
+		}
+		//Assert
+		assert.Equal(t, c.oTenants, tenants, fmt.Sprintf("%s :Tenants", msg))
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		for tenant, overflows := range c.oOverflows {
+			stats, ok := testQueue.TenantStats(tenant)
+			assert.True(t, ok, fmt.Sprintf("%s :Stats", msg))
+			assert.Equal(t, overflows, stats.Overflows, fmt.Sprintf("%s :Overflows", msg))
+		}
+		testQueue.Close()
+	}
+}
+
+//TestFairQueueWait will test waiting on a fair queue
+func TestFairQueueWait(t *testing.T) {
+	const name string = "FairQueueWait"
+	testQueue := NewFairQueue(FairConfig{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		testQueue.Enqueue("a", "later", 0)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	tenant, element, _, err := testQueue.DequeueWait(ctx)
+	assert.Nil(t, err, fmt.Sprintf("%s :Error", name))
+	assert.Equal(t, "a", tenant, fmt.Sprintf("%s :Tenant", name))
+	assert.Equal(t, "later", element, fmt.Sprintf("%s :Element", name))
+	assert.Equal(t, []string{"a"}, testQueue.GetTenants(), fmt.Sprintf("%s :Tenants", name))
+	//Closed
+	testQueue.Close()
+	_, _, _, err = testQueue.DequeueWait(ctx)
+	assert.Equal(t, ErrClosed, err, fmt.Sprintf("%s :Closed", name))
+}
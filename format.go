@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//On disk format versions
+//	1: segments and shared files without checksums
+//	2: records and slots carry checksums
+//	3: segments and shared files carry a versioned header
+const (
+	//FormatVersion is the on disk format written by this package
+	FormatVersion int = 3
+	//legacyVersion is assumed for files written before the header (records describe themselves)
+	legacyVersion int = 2
+	//segmentMagic identifies a versioned spill segment
+	segmentMagic string = "GQSG"
+	//segmentHeaderSize is the size of the segment header (magic, version, reserved)
+	segmentHeaderSize int = 16
+)
+
+var (
+	//ErrFormatVersion is returned for files written by a newer version of this package
+	ErrFormatVersion = errors.New("queue: unsupported format version")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Headers
+//---------------------------------------------------------------------------------------------------
+
+//segmentHeader returns the header of a new segment
+func segmentHeader() (header []byte) {
+	header = make([]byte, segmentHeaderSize)
+	copy(header, segmentMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(FormatVersion))
+	return
+}
+
+//readSegmentHeader returns the version of a segment and where its records start
+//Note: Segments without a header predate versioning, their records are read as they are
+func readSegmentHeader(file *os.File) (version int, start int64, err error) {
+	header := make([]byte, segmentHeaderSize)
+	n, rerr := file.ReadAt(header, 0)
+	if rerr != nil && rerr != io.EOF {
+		err = rerr
+		return
+	}
+	if n < len(segmentMagic) || string(header[:len(segmentMagic)]) != segmentMagic {
+		version = legacyVersion
+		return
+	}
+	if n < segmentHeaderSize {
+		err = ErrFormatVersion
+		return
+	}
+	version, start = int(binary.BigEndian.Uint32(header[4:])), int64(segmentHeaderSize)
+	if version > FormatVersion {
+		err = ErrFormatVersion
+	}
+	return
+}
+
+//sharedVersion returns the version of a shared queue file from its header
+func sharedVersion(header []byte) (version int) {
+	if version = int(binary.BigEndian.Uint32(header[32:])); version != 0 {
+		return
+	}
+	version = 1
+	if binary.BigEndian.Uint32(header[28:])&sharedChecked != 0 {
+		version = 2
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Migrate
+//---------------------------------------------------------------------------------------------------
+
+//Migrate rewrites persisted queue files in the current format
+//The path is either a spill directory or a shared queue file, as with Verify. Nothing may have the
+//files open while migrating. Damaged files are refused with a *CorruptionError; recover them first.
+//Consumed records are dropped along the way.
+func Migrate(path string) (err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	}
+	if info.IsDir() {
+		err = migrateSpill(path)
+		return
+	}
+	err = migrateShared(path)
+	return
+}
+
+//migrateSpill rewrites every segment of a spill directory
+func migrateSpill(dir string) (err error) {
+	var ids []uint64
+	if ids, err = listSegments(dir); err != nil {
+		return
+	}
+	for _, id := range ids {
+		if err = migrateSegment(segmentPath(dir, id)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//migrateSegment rewrites a single segment with a header and checked live records
+func migrateSegment(path string) (err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+	var version int
+	var start int64
+	if version, start, err = readSegmentHeader(file); err != nil || version == FormatVersion {
+		return
+	}
+	//Copy the live records
+	buffer := segmentHeader()
+	var report Report
+	var corruptions int
+	if _, corruptions, err = scanSegment(file, start, RecoverFail, false, &report, func(rec *record) {
+		if err != nil || rec.state&recordConsumed != 0 {
+			return
+		}
+		payload := make([]byte, rec.length)
+		if _, err = file.ReadAt(payload, rec.offset+int64(rec.headerSize())); err != nil {
+			return
+		}
+		rec.state = recordChecked
+		buffer = append(buffer, encodeHeader(rec, payload)...)
+		buffer = append(buffer, payload...)
+	}); err != nil {
+		return
+	}
+	if corruptions > 0 {
+		err = &CorruptionError{Report: report}
+		return
+	}
+	err = replaceFile(path, buffer)
+	return
+}
+
+//migrateShared rewrites a shared queue file with a version and checked slots
+func migrateShared(path string) (err error) {
+	var file *os.File
+	if file, err = os.OpenFile(path, os.O_RDWR, 0644); err != nil {
+		return
+	}
+	defer file.Close()
+	if err = lockFile(file); err != nil {
+		return
+	}
+	defer unlockFile(file)
+	var data []byte
+	if data, err = ioutil.ReadAll(file); err != nil {
+		return
+	}
+	//Check it is intact
+	var report Report
+	if err = verifyShared(path, &report); err != nil {
+		return
+	}
+	if !report.Healthy() {
+		err = &CorruptionError{Report: report}
+		return
+	}
+	version := sharedVersion(data)
+	if version == FormatVersion {
+		return
+	}
+	if version > FormatVersion {
+		err = ErrFormatVersion
+		return
+	}
+	//Copy the slots
+	size, slotSize, slotHeader := decodeSharedHeader(data)
+	buffer := make([]byte, sharedHeaderSize+size*(slotHeaderSize+slotChecksumSize+slotSize))
+	copy(buffer, data[:sharedHeaderSize])
+	binary.BigEndian.PutUint32(buffer[28:], sharedChecked)
+	binary.BigEndian.PutUint32(buffer[32:], uint32(FormatVersion))
+	for index := 0; index < size; index++ {
+		from := data[sharedHeaderSize+index*(slotHeader+slotSize):]
+		to := buffer[sharedHeaderSize+index*(slotHeaderSize+slotChecksumSize+slotSize):]
+		if from[0] != slotUsed {
+			continue
+		}
+		payload := from[slotHeader : slotHeader+int(binary.BigEndian.Uint32(from[4:]))]
+		copy(to, from[:slotHeaderSize])
+		binary.BigEndian.PutUint32(to[slotHeaderSize:], checksum(from[4:slotHeaderSize], payload))
+		copy(to[slotHeaderSize+slotChecksumSize:], payload)
+	}
+	err = replaceFile(path, buffer)
+	return
+}
+
+//replaceFile atomically replaces the contents of a file
+func replaceFile(path string, data []byte) (err error) {
+	tmp := path + ".tmp"
+	var file *os.File
+	if file, err = os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+		return
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	err = os.Rename(tmp, path)
+	return
+}
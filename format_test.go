@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//copyGolden copies a golden file (or directory) so it can be changed
+func copyGolden(t *testing.T, from, to string) {
+	info, err := os.Stat(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IsDir() {
+		if err = os.MkdirAll(to, 0755); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range infos {
+			copyGolden(t, filepath.Join(from, info.Name()), filepath.Join(to, info.Name()))
+		}
+		return
+	}
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(to, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Format
+//---------------------------------------------------------------------------------------------------
+
+//goldenVersions are the historical formats under testdata
+//Every version holds a spill with "a" and "c" live (a consumed "d" between them) and a shared
+//queue with "x" (priority 1) and "y" (priority 2).
+var goldenVersions = []int{1, 2, 3}
+
+//TestFormatSpill will test reading and migrating every historical spill format
+func TestFormatSpill(t *testing.T) {
+	const name string = "FormatSpill"
+	for _, version := range goldenVersions {
+		for _, migrate := range []bool{false, true} {
+			//Get the assert message base
+			msg := assertMsg(name, fmt.Sprintf("V%d_Migrate_%t", version, migrate))
+			dir, cleanup := tempDir(t)
+			defer cleanup()
+			copyGolden(t, filepath.Join("testdata", fmt.Sprintf("v%d", version), "spill"), dir)
+			//Migrate
+			if migrate {
+				if err := Migrate(dir); err != nil {
+					t.Fatal(err)
+				}
+				file, err := os.Open(segmentPath(dir, 0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				current, _, err := readSegmentHeader(file)
+				file.Close()
+				assert.NoError(t, err, fmt.Sprintf("%s :Header", msg))
+				assert.Equal(t, FormatVersion, current, fmt.Sprintf("%s :Version", msg))
+			}
+			//Verify
+			report, err := Verify(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, report.Healthy(), fmt.Sprintf("%s :Healthy", msg))
+			assert.Equal(t, 2, report.Live, fmt.Sprintf("%s :Live", msg))
+			//Read
+			testQueue := NewQueue(1, true)
+			defer testQueue.Close()
+			if err = testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+			elements, priorities := testQueue.Flush()
+			//Assert
+			assert.Equal(t, []interface{}{"a", "c"}, elements, fmt.Sprintf("%s :Elements", msg))
+			assert.Equal(t, []int{0, 0}, priorities, fmt.Sprintf("%s :Priorities", msg))
+		}
+	}
+}
+
+//TestFormatShared will test reading and migrating every historical shared queue format
+func TestFormatShared(t *testing.T) {
+	const name string = "FormatShared"
+	for _, version := range goldenVersions {
+		for _, migrate := range []bool{false, true} {
+			//Get the assert message base
+			msg := assertMsg(name, fmt.Sprintf("V%d_Migrate_%t", version, migrate))
+			dir, cleanup := tempDir(t)
+			defer cleanup()
+			path := filepath.Join(dir, "shared")
+			copyGolden(t, filepath.Join("testdata", fmt.Sprintf("v%d", version), "shared"), path)
+			//Migrate
+			if migrate {
+				if err := Migrate(path); err == ErrSharedUnsupported {
+					t.Skip(err)
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, FormatVersion, sharedVersion(data), fmt.Sprintf("%s :Version", msg))
+			}
+			//Verify
+			report, err := Verify(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, report.Healthy(), fmt.Sprintf("%s :Healthy", msg))
+			//Read
+			q, err := NewSharedQueue(path, SharedConfig{})
+			if err == ErrSharedUnsupported {
+				t.Skip(err)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			elements, priorities := q.Flush()
+			q.Close()
+			//Assert
+			assert.Equal(t, []interface{}{"y", "x"}, elements, fmt.Sprintf("%s :Elements", msg))
+			assert.Equal(t, []int{2, 1}, priorities, fmt.Sprintf("%s :Priorities", msg))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package queue
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//HookKind is a point in the life of a queue where hooks run
+//Hooks of a kind run in the order they were added and the first error stops the chain. What an
+//error means depends on the kind:
+//	BeforeEnqueue: the element is rejected and Enqueue reports overflow
+//	BeforeDequeue: the dequeue is refused (the element stays) and Dequeue reports underflow
+//	AfterDequeue: the element (already out) is dropped and Dequeue reports underflow
+//	Any other: nothing beyond stopping the chain
+type HookKind int
+
+const (
+	//BeforeEnqueue runs before an element is enqueued (may change the element and priority)
+	BeforeEnqueue HookKind = iota
+	//AfterEnqueue runs once an element is enqueued
+	AfterEnqueue
+	//BeforeDequeue runs before the head is dequeued
+	BeforeDequeue
+	//AfterDequeue runs once an element is dequeued (may change the element and priority returned)
+	AfterDequeue
+	//OnOverflow runs when an element is refused as the queue is full
+	OnOverflow
+	//OnEvict runs when an element is written to disk for lack of room in memory
+	OnEvict
+	//OnFlush runs for every element removed by Flush or Resize
+	OnFlush
+	//OnResize runs when the queue is resized
+	OnResize
+	//OnClose runs when the queue is closed
+	OnClose
+)
+
+//HookEvent is what a hook sees
+type HookEvent struct {
+	Kind     HookKind    //where the hook runs
+	Element  interface{} //the element (nil for OnResize and OnClose)
+	Priority int         //the priority of the element
+	Length   int         //the length of the queue when the hook runs
+	Size     int         //the size of the queue when the hook runs
+}
+
+//Hook is called at a point in the life of a queue
+//Hooks run with the queue locked and must not call back into it.
+type Hook func(event *HookEvent) (err error)
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Hooks provides methods of intercepting the queue
+type Hooks interface {
+	//AddHook will add a hook to run at a point in the life of the queue
+	AddHook(kind HookKind, hook Hook)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hooks Implementation
+//---------------------------------------------------------------------------------------------------
+
+//AddHook will add a hook to run at a point in the life of the queue
+func (q *queue) AddHook(kind HookKind, hook Hook) {
+	q.Lock()
+	defer q.Unlock()
+	if q.hooks == nil {
+		q.hooks = make(map[HookKind][]Hook)
+	}
+	q.hooks[kind] = append(q.hooks[kind], hook)
+}
+
+//runHooks will run the chain of a kind (locked)
+func (q *queue) runHooks(kind HookKind, element interface{}, priority int) (event *HookEvent, err error) {
+	event = &HookEvent{Kind: kind, Element: element, Priority: priority}
+	chain := q.hooks[kind]
+	if len(chain) == 0 {
+		return
+	}
+	event.Length, event.Size = q.length(), q.size
+	for _, hook := range chain {
+		if err = hook(event); err != nil {
+			return
+		}
+	}
+	return
+}
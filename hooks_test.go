@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Hooks
+//---------------------------------------------------------------------------------------------------
+
+//TestHooks will test the hook chains
+func TestHooks(t *testing.T) {
+	const name string = "Hooks"
+	errReject := errors.New("reject")
+	cases := map[string]struct {
+		iKind       HookKind
+		iHooks      []Hook
+		iElements   []interface{}
+		oOverflows  int
+		oElements   []interface{}
+		oPriorities []int
+		oLength     int
+	}{
+		"Before_Enqueue_Modify": {
+			iKind: BeforeEnqueue,
+			iHooks: []Hook{
+				func(e *HookEvent) error { e.Priority = e.Element.(int); return nil },
+				func(e *HookEvent) error { e.Element = e.Element.(int) * 10; return nil },
+			},
+			iElements:   []interface{}{1, 2, 3},
+			oElements:   []interface{}{30, 20, 10},
+			oPriorities: []int{3, 2, 1},
+		},
+		"Before_Enqueue_Reject": {
+			iKind: BeforeEnqueue,
+			iHooks: []Hook{
+				func(e *HookEvent) error {
+					if e.Element.(int)%2 == 0 {
+						return errReject
+					}
+					return nil
+				},
+			},
+			iElements:   []interface{}{1, 2, 3},
+			oOverflows:  1,
+			oElements:   []interface{}{1, 3},
+			oPriorities: []int{0, 0},
+		},
+		"Before_Dequeue_Refuse": {
+			iKind:     BeforeDequeue,
+			iHooks:    []Hook{func(e *HookEvent) error { return errReject }},
+			iElements: []interface{}{1, 2},
+			oLength:   2,
+		},
+		"After_Dequeue_Drop": {
+			iKind: AfterDequeue,
+			iHooks: []Hook{
+				func(e *HookEvent) error {
+					if e.Element.(int) == 1 {
+						return errReject
+					}
+					return nil
+				},
+			},
+			iElements:   []interface{}{1, 2},
+			oElements:   []interface{}{2},
+			oPriorities: []int{0},
+		},
+		"Stop_Chain": {
+			iKind: AfterDequeue,
+			iHooks: []Hook{
+				func(e *HookEvent) error { return errReject },
+				func(e *HookEvent) error { t.Fatal("chain not stopped"); return nil },
+			},
+			iElements: []interface{}{1},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(len(c.iElements), true)
+		defer testQueue.Close()
+		for _, hook := range c.iHooks {
+			testQueue.AddHook(c.iKind, hook)
+		}
+		//Enqueue
+		overflows := 0
+		for _, element := range c.iElements {
+			if testQueue.Enqueue(element) {
+				overflows++
+			}
+		}
+		//Dequeue
+		var elements []interface{}
+		var priorities []int
+		for range c.iElements {
+			element, priority, underflow := testQueue.DequeuePriority()
+			if underflow {
+				continue
+			}
+			elements = append(elements, element)
+			priorities = append(priorities, priority)
+		}
+		//Assert
+		assert.Equal(t, c.oOverflows, overflows, fmt.Sprintf("%s :Overflows", msg))
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oPriorities, priorities, fmt.Sprintf("%s :Priorities", msg))
+		assert.Equal(t, c.oLength, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+	}
+}
+
+//TestHooksLifecycle will test the observing hooks
+func TestHooksLifecycle(t *testing.T) {
+	const name string = "HooksLifecycle"
+	var kinds []HookKind
+	observe := func(e *HookEvent) error {
+		kinds = append(kinds, e.Kind)
+		return nil
+	}
+	//Create Queue
+	testQueue := NewQueue(1, true)
+	for kind := BeforeEnqueue; kind <= OnClose; kind++ {
+		testQueue.AddHook(kind, observe)
+	}
+	testQueue.Enqueue(1)
+	testQueue.Enqueue(2)
+	testQueue.Dequeue()
+	testQueue.Enqueue(3)
+	testQueue.Flush()
+	testQueue.Resize(2)
+	testQueue.Close()
+	//Assert
+	assert.Equal(t, []HookKind{
+		BeforeEnqueue, AfterEnqueue,
+		BeforeEnqueue, OnOverflow,
+		BeforeDequeue, AfterDequeue,
+		BeforeEnqueue, AfterEnqueue,
+		OnFlush,
+		OnResize,
+		OnClose,
+	}, kinds, name)
+}
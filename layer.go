@@ -0,0 +1,248 @@
+package queue
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+const (
+	//DefaultCompressMin is the smallest encoded element (bytes) worth compressing
+	DefaultCompressMin int = 128
+	//compressStored marks a record left as is
+	compressStored byte = 0
+	//compressFlate marks a record compressed with flate
+	compressFlate byte = 1
+	//keyIDSize is the size of the key id in front of a sealed record
+	keyIDSize int = 4
+)
+
+var (
+	//ErrUnknownKey is returned when a record was sealed with a key that is not known
+	ErrUnknownKey = errors.New("queue: unknown encryption key")
+	//ErrLayerData is returned when a record is not what a layer expects
+	ErrLayerData = errors.New("queue: invalid layer data")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Layer transforms encoded elements on their way to and from storage
+//Layers are given in write order; reading unwraps them in reverse.
+type Layer interface {
+	//Wrap will transform an encoded element before it is stored
+	Wrap(data []byte) (wrapped []byte, err error)
+	//Unwrap will undo Wrap
+	Unwrap(wrapped []byte) (data []byte, err error)
+}
+
+//layered returns a codec that runs the layers after codec
+func layered(codec Codec, layers []Layer) Codec {
+	if len(layers) == 0 {
+		return codec
+	}
+	return &layeredCodec{codec: codec, layers: layers}
+}
+
+//layeredCodec is a codec followed by layers
+type layeredCodec struct {
+	codec  Codec
+	layers []Layer
+}
+
+//Encode will encode a single element and wrap it in the layers
+func (l *layeredCodec) Encode(element interface{}) (data []byte, err error) {
+	if data, err = l.codec.Encode(element); err != nil {
+		return
+	}
+	for _, layer := range l.layers {
+		if data, err = layer.Wrap(data); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//Decode will unwrap the layers and decode a single element
+func (l *layeredCodec) Decode(data []byte) (element interface{}, err error) {
+	for index := len(l.layers) - 1; index >= 0; index-- {
+		if data, err = l.layers[index].Unwrap(data); err != nil {
+			return
+		}
+	}
+	element, err = l.codec.Decode(data)
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Compression
+//---------------------------------------------------------------------------------------------------
+
+//Ensure the implementation
+var _ Layer = &Compression{}
+
+//Compression is a Layer compressing each record with compress/flate
+//Records that are small or do not shrink are stored as is.
+type Compression struct {
+	Level int //flate level (flate.DefaultCompression if 0)
+	Min   int //smallest record to compress (DefaultCompressMin if 0)
+}
+
+//Wrap will compress a record
+func (c *Compression) Wrap(data []byte) (wrapped []byte, err error) {
+	min, level := c.Min, c.Level
+	if min == 0 {
+		min = DefaultCompressMin
+	}
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	//Check if worth it
+	if len(data) >= min {
+		var buffer bytes.Buffer
+		buffer.WriteByte(compressFlate)
+		var writer *flate.Writer
+		if writer, err = flate.NewWriter(&buffer, level); err != nil {
+			return
+		}
+		if _, err = writer.Write(data); err != nil {
+			return
+		}
+		if err = writer.Close(); err != nil {
+			return
+		}
+		if buffer.Len() < len(data)+1 {
+			wrapped = buffer.Bytes()
+			return
+		}
+	}
+	wrapped = append([]byte{compressStored}, data...)
+	return
+}
+
+//Unwrap will decompress a record
+func (c *Compression) Unwrap(wrapped []byte) (data []byte, err error) {
+	if len(wrapped) < 1 {
+		err = ErrLayerData
+		return
+	}
+	switch wrapped[0] {
+	case compressStored:
+		data = wrapped[1:]
+	case compressFlate:
+		reader := flate.NewReader(bytes.NewReader(wrapped[1:]))
+		defer reader.Close()
+		data, err = ioutil.ReadAll(reader)
+	default:
+		err = ErrLayerData
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Encryption
+//---------------------------------------------------------------------------------------------------
+
+//Ensure the implementation
+var _ Layer = &Encryption{}
+
+//Encryption is a Layer sealing each record with AES-GCM
+//Every record is tagged with the id of the key that sealed it so keys can be rotated: new records
+//use the current key while older ones open with whichever key they name.
+type Encryption struct {
+	sync.RWMutex
+	current uint32                 //id of the key sealing new records
+	aeads   map[uint32]cipher.AEAD //keys by id
+}
+
+//NewEncryption returns an Encryption sealing with the key current
+//Keys must be 16, 24 or 32 bytes (AES-128, AES-192 or AES-256).
+func NewEncryption(current uint32, keys map[uint32][]byte) (e *Encryption, err error) {
+	if _, ok := keys[current]; !ok {
+		err = ErrUnknownKey
+		return
+	}
+	e = &Encryption{current: current, aeads: make(map[uint32]cipher.AEAD)}
+	for id, key := range keys {
+		if err = e.add(id, key); err != nil {
+			e = nil
+			return
+		}
+	}
+	return
+}
+
+//Rotate will add a key and seal new records with it
+func (e *Encryption) Rotate(id uint32, key []byte) (err error) {
+	e.Lock()
+	defer e.Unlock()
+	if err = e.add(id, key); err != nil {
+		return
+	}
+	e.current = id
+	return
+}
+
+//Wrap will seal a record with the current key
+func (e *Encryption) Wrap(data []byte) (wrapped []byte, err error) {
+	e.RLock()
+	id, aead := e.current, e.aeads[e.current]
+	e.RUnlock()
+	//Key id, nonce, sealed data (the key id is authenticated)
+	wrapped = make([]byte, keyIDSize+aead.NonceSize(), keyIDSize+aead.NonceSize()+len(data)+aead.Overhead())
+	binary.BigEndian.PutUint32(wrapped, id)
+	if _, err = io.ReadFull(rand.Reader, wrapped[keyIDSize:]); err != nil {
+		return
+	}
+	wrapped = aead.Seal(wrapped, wrapped[keyIDSize:], data, wrapped[:keyIDSize])
+	return
+}
+
+//Unwrap will open a record with the key it names
+func (e *Encryption) Unwrap(wrapped []byte) (data []byte, err error) {
+	if len(wrapped) < keyIDSize {
+		err = ErrLayerData
+		return
+	}
+	id := binary.BigEndian.Uint32(wrapped)
+	e.RLock()
+	aead, ok := e.aeads[id]
+	e.RUnlock()
+	if !ok {
+		err = ErrUnknownKey
+		return
+	}
+	if len(wrapped) < keyIDSize+aead.NonceSize() {
+		err = ErrLayerData
+		return
+	}
+	nonce := wrapped[keyIDSize : keyIDSize+aead.NonceSize()]
+	data, err = aead.Open(nil, nonce, wrapped[keyIDSize+aead.NonceSize():], wrapped[:keyIDSize])
+	return
+}
+
+//add will add a key (locked)
+func (e *Encryption) add(id uint32, key []byte) (err error) {
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = cipher.NewGCM(block); err != nil {
+		return
+	}
+	e.aeads[id] = aead
+	return
+}
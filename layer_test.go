@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Layer
+//---------------------------------------------------------------------------------------------------
+
+//TestLayers will test wrapping and unwrapping records
+func TestLayers(t *testing.T) {
+	const name string = "Layers"
+	keys := map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}
+	encryption, err := NewEncryption(1, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		iLayers  []Layer
+		iElement interface{}
+		oSmaller bool
+	}{
+		"None": {
+			iLayers:  nil,
+			iElement: "small",
+		},
+		"Compression_Small": {
+			iLayers:  []Layer{&Compression{}},
+			iElement: "small",
+		},
+		"Compression_Large": {
+			iLayers:  []Layer{&Compression{}},
+			iElement: strings.Repeat("large", 100),
+			oSmaller: true,
+		},
+		"Encryption": {
+			iLayers:  []Layer{encryption},
+			iElement: "secret",
+		},
+		"Compression_Encryption": {
+			iLayers:  []Layer{&Compression{}, encryption},
+			iElement: strings.Repeat("secret", 100),
+			oSmaller: true,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		codec := layered(GobCodec{}, c.iLayers)
+		//Encode
+		plain, err := GobCodec{}.Encode(c.iElement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := codec.Encode(c.iElement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Decode
+		element, err := codec.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Assert
+		assert.Equal(t, c.iElement, element, fmt.Sprintf("%s :Element", msg))
+		assert.Equal(t, c.oSmaller, len(data) < len(plain), fmt.Sprintf("%s :Smaller", msg))
+	}
+}
+
+//TestEncryptionRotate will test reading records sealed with an older key
+func TestEncryptionRotate(t *testing.T) {
+	const name string = "EncryptionRotate"
+	encryption, err := NewEncryption(1, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := encryption.Wrap([]byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Rotate
+	assert.Error(t, encryption.Rotate(2, []byte("short")), fmt.Sprintf("%s :Invalid", name))
+	if err = encryption.Rotate(2, bytes.Repeat([]byte{2}, 16)); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := encryption.Wrap([]byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Assert
+	data, err := encryption.Unwrap(old)
+	assert.NoError(t, err, fmt.Sprintf("%s :Old", name))
+	assert.Equal(t, []byte("old"), data, fmt.Sprintf("%s :Old", name))
+	data, err = encryption.Unwrap(fresh)
+	assert.NoError(t, err, fmt.Sprintf("%s :New", name))
+	assert.Equal(t, []byte("new"), data, fmt.Sprintf("%s :New", name))
+	//Without the old key
+	other, err := NewEncryption(2, map[uint32][]byte{2: bytes.Repeat([]byte{2}, 16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = other.Unwrap(old)
+	assert.Equal(t, ErrUnknownKey, err, fmt.Sprintf("%s :Unknown", name))
+	//Tampered
+	fresh[len(fresh)-1] ^= 0xFF
+	_, err = encryption.Unwrap(fresh)
+	assert.Error(t, err, fmt.Sprintf("%s :Tampered", name))
+}
+
+//TestSpillLayers will test spilling through layers
+func TestSpillLayers(t *testing.T) {
+	const name string = "SpillLayers"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	encryption, err := NewEncryption(7, map[uint32][]byte{7: bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQueue := NewQueue(1, true)
+	defer testQueue.Close()
+	if err = testQueue.Spill(SpillConfig{Dir: dir, Layers: []Layer{&Compression{}, encryption}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, element := range []interface{}{"a", "b", "c"} {
+		testQueue.Enqueue(element)
+	}
+	//Assert
+	assert.Equal(t, 2, testQueue.GetSpilled(), fmt.Sprintf("%s :Spilled", name))
+	elements, _ := testQueue.Flush()
+	assert.Equal(t, []interface{}{"a", "b", "c"}, elements, fmt.Sprintf("%s :Elements", name))
+}
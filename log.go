@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+)
+
+const (
+	//DefaultLogRate is the default number of per element logs a second
+	DefaultLogRate float64 = 10
+	//DefaultLogBurst is the default number of per element logs allowed at once
+	DefaultLogBurst int = 10
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//LogConfig is the config of queue logging
+type LogConfig struct {
+	Logger *slog.Logger //where to log (nil turns logging off)
+	Name   string       //name of the queue (the "queue" attribute)
+	Rate   float64      //per element logs a second (0 is DefaultLogRate)
+	Burst  int          //per element logs allowed at once (0 is DefaultLogBurst)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Logging provides methods of logging what the queue does
+type Logging interface {
+	//SetLogger will set (or with a nil Logger clear) the logger
+	SetLogger(config LogConfig)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Logging Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetLogger will set (or with a nil Logger clear) the logger
+//Overflows log at warn, close, resize, pause and resume at info and every element (enqueue, dequeue, evict and
+//flush) at debug. Per element logs are rate limited; the next one let through carries how many
+//were suppressed.
+func (q *queue) SetLogger(config LogConfig) {
+	q.Lock()
+	defer q.Unlock()
+	if config.Logger == nil {
+		q.logger = nil
+		return
+	}
+	if config.Rate <= 0 {
+		config.Rate = DefaultLogRate
+	}
+	if config.Burst <= 0 {
+		config.Burst = DefaultLogBurst
+	}
+	q.logger = &logger{
+		Logger:  config.Logger.With(slog.String("queue", config.Name)),
+		limiter: newLimiter(config.Rate, config.Burst),
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//logger is a rate limited logger
+type logger struct {
+	*slog.Logger
+	limiter    *limiter
+	suppressed uint64 //per element logs not let through since the last one
+}
+
+//log will log a change (locked)
+func (q *queue) log(change Change) {
+	l := q.logger
+	if l == nil {
+		return
+	}
+	ctx := context.Background()
+	attrs := []slog.Attr{slog.Int("length", change.Length)}
+	switch change.Kind {
+	case Overflowed:
+		attrs = append(attrs, slog.Uint64("handle", change.Handle), slog.Int("priority", change.Priority))
+		l.LogAttrs(ctx, slog.LevelWarn, "queue overflow", attrs...)
+	case Corrupted:
+		attrs = append(attrs, slog.Uint64("handle", change.Handle), slog.Int("priority", change.Priority))
+		l.LogAttrs(ctx, slog.LevelWarn, "element corrupted", attrs...)
+	case Resized:
+		attrs = append(attrs, slog.Int("size", q.size))
+		l.LogAttrs(ctx, slog.LevelInfo, "queue resized", attrs...)
+	case Closed, Paused, Resumed:
+		l.LogAttrs(ctx, slog.LevelInfo, "queue "+change.Kind.String(), attrs...)
+	default:
+		if !l.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		if !l.limiter.allow(change.Time) {
+			l.suppressed++
+			return
+		}
+		attrs = append(attrs, slog.Uint64("handle", change.Handle), slog.Int("priority", change.Priority))
+		if l.suppressed > 0 {
+			attrs = append(attrs, slog.Uint64("suppressed", l.suppressed))
+			l.suppressed = 0
+		}
+		l.LogAttrs(ctx, slog.LevelDebug, "element "+change.Kind.String(), attrs...)
+	}
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Logging
+//---------------------------------------------------------------------------------------------------
+
+//logLine is a decoded JSON log line
+type logLine struct {
+	Level    string `json:"level"`
+	Msg      string `json:"msg"`
+	Queue    string `json:"queue"`
+	Length   int    `json:"length"`
+	Priority int    `json:"priority"`
+	Handle   uint64 `json:"handle"`
+}
+
+//TestSetLogger will test what is logged and at what level
+func TestSetLogger(t *testing.T) {
+	const name string = "SetLogger"
+	cases := map[string]struct {
+		iLevel slog.Level
+		iBurst int
+		oLines []logLine
+	}{
+		"Debug": {
+			iLevel: slog.LevelDebug,
+			iBurst: 10,
+			oLines: []logLine{
+				{Level: "DEBUG", Msg: "element enqueued", Queue: "jobs", Length: 1, Priority: 2, Handle: 0},
+				{Level: "WARN", Msg: "queue overflow", Queue: "jobs", Length: 1, Priority: 0, Handle: 1},
+				{Level: "DEBUG", Msg: "element dequeued", Queue: "jobs", Length: 0, Priority: 2, Handle: 0},
+				{Level: "INFO", Msg: "queue resized", Queue: "jobs"},
+				{Level: "INFO", Msg: "queue closed", Queue: "jobs"},
+			},
+		},
+		"Rate_Limited": {
+			iLevel: slog.LevelDebug,
+			iBurst: 1,
+			oLines: []logLine{
+				{Level: "DEBUG", Msg: "element enqueued", Queue: "jobs", Length: 1, Priority: 2, Handle: 0},
+				{Level: "WARN", Msg: "queue overflow", Queue: "jobs", Length: 1, Priority: 0, Handle: 1},
+				{Level: "INFO", Msg: "queue resized", Queue: "jobs"},
+				{Level: "INFO", Msg: "queue closed", Queue: "jobs"},
+			},
+		},
+		"Info": {
+			iLevel: slog.LevelInfo,
+			oLines: []logLine{
+				{Level: "WARN", Msg: "queue overflow", Queue: "jobs", Length: 1, Priority: 0, Handle: 1},
+				{Level: "INFO", Msg: "queue resized", Queue: "jobs"},
+				{Level: "INFO", Msg: "queue closed", Queue: "jobs"},
+			},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		var buf bytes.Buffer
+		testQueue := NewQueue(1, true)
+		testQueue.SetLogger(LogConfig{
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: c.iLevel})),
+			Name:   "jobs",
+			Rate:   0.001,
+			Burst:  c.iBurst,
+		})
+		testQueue.EnqueuePriority(1, 2)
+		testQueue.Enqueue(2)
+		testQueue.Dequeue()
+		testQueue.Resize(2)
+		testQueue.Close()
+		//Decode
+		var lines []logLine
+		decoder := json.NewDecoder(&buf)
+		for decoder.More() {
+			var line logLine
+			if err := decoder.Decode(&line); err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		//Assert
+		assert.Equal(t, c.oLines, lines, fmt.Sprintf("%s :Lines", msg))
+	}
+}
+
+//TestLimiter will test the token bucket
+func TestLimiter(t *testing.T) {
+	const name string = "Limiter"
+	start := time.Unix(0, 0)
+	cases := map[string]struct {
+		iRate  float64
+		iBurst int
+		iAfter []time.Duration //times of the takes from start
+		oAllow []bool
+	}{
+		"Burst": {
+			iRate:  1,
+			iBurst: 2,
+			iAfter: []time.Duration{0, 0, 0},
+			oAllow: []bool{true, true, false},
+		},
+		"Refill": {
+			iRate:  2,
+			iBurst: 1,
+			iAfter: []time.Duration{0, 100 * time.Millisecond, 500 * time.Millisecond, 600 * time.Millisecond},
+			oAllow: []bool{true, false, true, false},
+		},
+		"Capped": {
+			iRate:  10,
+			iBurst: 2,
+			iAfter: []time.Duration{0, time.Hour, time.Hour, time.Hour},
+			oAllow: []bool{true, true, true, false},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Take
+		l := newLimiter(c.iRate, c.iBurst)
+		var allow []bool
+		for _, after := range c.iAfter {
+			allow = append(allow, l.allow(start.Add(after)))
+		}
+		//Assert
+		assert.Equal(t, c.oAllow, allow, fmt.Sprintf("%s :Allow", msg))
+	}
+}
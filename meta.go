@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//Meta is the metadata of an element
+type Meta struct {
+	Enqueued time.Time         //when the element was enqueued
+	Handle   uint64            //handle of the element (its sequence number)
+	Attempts int               //times the element has been dequeued (this one included)
+	Headers  map[string]string //user headers
+	Deadline time.Time         //when it must be dequeued by (zero if none)
+	Missed   bool              //past its deadline
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Metadata provides methods of working with element metadata
+type Metadata interface {
+	//PeekWithMeta will peek the head element with its metadata
+	PeekWithMeta() (element interface{}, priority int, meta Meta, empty bool)
+	//DequeueWithMeta will dequeue a single element with its metadata
+	DequeueWithMeta() (element interface{}, priority int, meta Meta, underflow bool)
+	//EnqueueWithMeta will enqueue a single element with attempts, headers and deadline
+	EnqueueWithMeta(element interface{}, priority int, meta Meta) (overflow bool)
+}
+
+//Remove provides methods of removing a single element
+type Remove interface {
+	//Remove will remove a single element (in memory or on disk) by handle
+	Remove(handle uint64) (element interface{}, priority int, found bool)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Metadata Implementation
+//---------------------------------------------------------------------------------------------------
+
+//PeekWithMeta will peek the head element with its metadata
+func (q *queue) PeekWithMeta() (element interface{}, priority int, meta Meta, empty bool) {
+	q.Lock()
+	defer q.Unlock()
+	q.age()
+	//Check if queue is empty
+	if empty = q.checkIfEmpty(); empty {
+		return
+	}
+	//Get first element
+	container := q.containers[0]
+	element, priority, meta = container.element, container.priority, container.meta()
+	return
+}
+
+//DequeueWithMeta will dequeue a single element with its metadata
+func (q *queue) DequeueWithMeta() (element interface{}, priority int, meta Meta, underflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Dequeue
+	var head *container
+	if underflow, element, priority, head = q.dequeue(); !underflow {
+		meta = head.meta()
+	}
+	return
+}
+
+//EnqueueWithMeta will enqueue a single element with attempts, headers and deadline
+//The enqueue time and handle are new, so an element dequeued and put back (a retry) keeps its
+//attempts, headers and deadline but goes to the back of its priority.
+func (q *queue) EnqueueWithMeta(element interface{}, priority int, meta Meta) (overflow bool) {
+	overflow = q.requeue(element, priority, meta, nil)
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Remove Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Remove will remove a single element (in memory or on disk) by handle
+//The handle is the one in Meta and in changes. Hooks do not run; subscribers see Removed. A
+//spilled element that can not be read back is found with a nil element and reported as Corrupted.
+func (q *queue) Remove(handle uint64) (element interface{}, priority int, found bool) {
+	q.Lock()
+	defer q.Unlock()
+	var removed *container
+	//Memory
+	for index, container := range q.containers {
+		if container.seq == handle {
+			removed = container
+			q.containers = append(q.containers[:index], q.containers[index+1:]...)
+			q.fill()
+			break
+		}
+	}
+	//Disk
+	if removed == nil && q.spill != nil {
+		if index, ok := q.spill.find(handle); ok {
+			//Gone even if it can not be read (reported as corrupted)
+			var err error
+			if removed, err = q.spill.take(index); err != nil {
+				q.corrupt(removed, err)
+				q.watermark()
+				found = true
+				return
+			}
+		}
+	}
+	if removed == nil {
+		return
+	}
+	element, priority, found = removed.element, removed.priority, true
+	q.publish(Removed, removed)
+	q.watermark()
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//requeue will enqueue a single element with its metadata and what it carried (a retry)
+func (q *queue) requeue(element interface{}, priority int, meta Meta, carried *carried) (overflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Enqueue
+	overflow = q.enqueue(&container{
+		element:  element,
+		priority: priority,
+		carried:  carried,
+		attempts: meta.Attempts,
+		headers:  copyHeaders(meta.Headers),
+		deadline: meta.Deadline,
+	})
+	//Trigger signal
+	q.triggerSignal()
+	return
+}
+
+//meta returns the metadata of a container
+func (c *container) meta() (meta Meta) {
+	meta = Meta{
+		Enqueued: c.enqueued,
+		Handle:   c.seq,
+		Attempts: c.attempts,
+		Headers:  copyHeaders(c.headers),
+		Deadline: c.deadline,
+		Missed:   c.missed,
+	}
+	return
+}
+
+//copyHeaders returns a copy of headers (nil if none)
+func copyHeaders(headers map[string]string) (copied map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+	copied = make(map[string]string, len(headers))
+	for key, value := range headers {
+		copied[key] = value
+	}
+	return
+}
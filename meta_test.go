@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Metadata
+//---------------------------------------------------------------------------------------------------
+
+//TestMetadata will test element metadata through dequeue and retry
+func TestMetadata(t *testing.T) {
+	const name string = "Metadata"
+	cases := map[string]struct {
+		iSpill    bool
+		iHeaders  map[string]string
+		iRetries  int
+		oAttempts []int
+		oHandles  []uint64
+	}{
+		"No_Retry": {
+			iHeaders:  map[string]string{"tenant": "acme"},
+			oAttempts: []int{1},
+			oHandles:  []uint64{1},
+		},
+		"Retry": {
+			iHeaders:  map[string]string{"tenant": "acme"},
+			iRetries:  2,
+			oAttempts: []int{1, 2, 3},
+			oHandles:  []uint64{1, 2, 3},
+		},
+		"Retry_Spilled": {
+			iSpill:    true,
+			iRetries:  1,
+			oAttempts: []int{1, 2},
+			oHandles:  []uint64{1, 3},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue (a spilled one only holds one in memory)
+		size := 2
+		if c.iSpill {
+			size = 1
+		}
+		testQueue := NewQueue(size, true)
+		if c.iSpill {
+			dir, cleanup := tempDir(t)
+			defer cleanup()
+			if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		//A high priority element ahead of the one under test
+		testQueue.EnqueuePriority("first", 9)
+		testQueue.EnqueueWithMeta("job", 1, Meta{Headers: c.iHeaders})
+		testQueue.Dequeue()
+		//Peek does not count as an attempt
+		_, _, peeked, _ := testQueue.PeekWithMeta()
+		assert.Equal(t, 0, peeked.Attempts, fmt.Sprintf("%s :Peek attempts", msg))
+		//Dequeue and put back
+		var attempts []int
+		var handles []uint64
+		for i := 0; i <= c.iRetries; i++ {
+			element, priority, meta, underflow := testQueue.DequeueWithMeta()
+			if underflow {
+				t.Fatalf("%s :underflow", msg)
+			}
+			assert.Equal(t, "job", element, fmt.Sprintf("%s :Element", msg))
+			assert.Equal(t, 1, priority, fmt.Sprintf("%s :Priority", msg))
+			assert.Equal(t, c.iHeaders, meta.Headers, fmt.Sprintf("%s :Headers", msg))
+			assert.False(t, meta.Enqueued.IsZero(), fmt.Sprintf("%s :Enqueued", msg))
+			attempts = append(attempts, meta.Attempts)
+			handles = append(handles, meta.Handle)
+			if i < c.iRetries {
+				if c.iSpill {
+					//Push the retry to disk behind a better element
+					testQueue.EnqueuePriority("better", 5)
+					testQueue.EnqueueWithMeta(element, priority, meta)
+					testQueue.Dequeue()
+					continue
+				}
+				testQueue.EnqueueWithMeta(element, priority, meta)
+			}
+		}
+		//Assert
+		assert.Equal(t, c.oAttempts, attempts, fmt.Sprintf("%s :Attempts", msg))
+		assert.Equal(t, c.oHandles, handles, fmt.Sprintf("%s :Handles", msg))
+		testQueue.Close()
+	}
+}
+
+//TestMetadataHeadersCopied will test headers not being shared with the caller
+func TestMetadataHeadersCopied(t *testing.T) {
+	const name string = "MetadataHeadersCopied"
+	//Create Queue
+	testQueue := NewQueue(1, true)
+	defer testQueue.Close()
+	headers := map[string]string{"a": "1"}
+	testQueue.EnqueueWithMeta(1, 0, Meta{Headers: headers})
+	headers["a"] = "2"
+	_, _, meta, _ := testQueue.PeekWithMeta()
+	meta.Headers["a"] = "3"
+	_, _, meta, _ = testQueue.DequeueWithMeta()
+	//Assert
+	assert.Equal(t, map[string]string{"a": "1"}, meta.Headers, name)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Remove
+//---------------------------------------------------------------------------------------------------
+
+//TestRemove will test removing a single element by handle
+func TestRemove(t *testing.T) {
+	const name string = "Remove"
+	cases := map[string]struct {
+		iHandle   uint64
+		oElement  interface{}
+		oPriority int
+		oFound    bool
+		oElements []interface{}
+	}{
+		"Memory": {
+			iHandle:   0,
+			oElement:  "a",
+			oPriority: 3,
+			oFound:    true,
+			oElements: []interface{}{"b", "c"},
+		},
+		"Disk": {
+			iHandle:   2,
+			oElement:  "c",
+			oPriority: 1,
+			oFound:    true,
+			oElements: []interface{}{"a", "b"},
+		},
+		"Missing": {
+			iHandle:   7,
+			oElements: []interface{}{"a", "b", "c"},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue (only two in memory)
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+		testQueue := NewQueue(2, true)
+		if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+		testQueue.EnqueuePriority("a", 3)
+		testQueue.EnqueuePriority("b", 2)
+		testQueue.EnqueuePriority("c", 1)
+		//Remove
+		element, priority, found := testQueue.Remove(c.iHandle)
+		//Drain
+		var elements []interface{}
+		for {
+			element, underflow := testQueue.Dequeue()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+		}
+		//Assert
+		assert.Equal(t, c.oElement, element, fmt.Sprintf("%s :Element", msg))
+		assert.Equal(t, c.oPriority, priority, fmt.Sprintf("%s :Priority", msg))
+		assert.Equal(t, c.oFound, found, fmt.Sprintf("%s :Found", msg))
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		testQueue.Close()
+	}
+}
@@ -0,0 +1,303 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultMLFQLevels is the default number of MLFQ levels
+	DefaultMLFQLevels int = 3
+	//DefaultMLFQQuantum is the default time quantum of the top MLFQ level
+	DefaultMLFQQuantum time.Duration = time.Second
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//MLFQConfig is the config of a multi-level feedback queue
+type MLFQConfig struct {
+	Levels int             //levels (0 is DefaultMLFQLevels)
+	Size   int             //size of each level (0 is DefaultSize)
+	Quanta []time.Duration //time a job may run at each level (missing levels double the one above, the top is DefaultMLFQQuantum if none)
+	Boost  time.Duration   //how often every job goes back to the top level (0 is never)
+	//OnDrop is called with a job nacked or out of time that the level below had no room for (optional)
+	OnDrop func(job *MLFQJob)
+}
+
+//MLFQJob is a job taken from a multi-level feedback queue
+//It must be acked or nacked within its quantum; past the deadline it is demoted as if nacked.
+//A job demoted to a full level is dropped (see MLFQConfig.OnDrop).
+type MLFQJob struct {
+	Element  interface{}
+	Priority int
+	Meta     Meta
+	Level    int       //level it was taken from (0 is the top)
+	Deadline time.Time //end of its quantum
+	mlfq     *MLFQ
+	timer    *time.Timer
+}
+
+//---------------------------------------------------------------------------------------------------
+// MLFQ
+//---------------------------------------------------------------------------------------------------
+
+//MLFQ is a multi-level feedback queue scheduler
+//New jobs start in the top level and are taken top level first (by priority within a level). A
+//job nacked or out of time drops a level, and every Boost all jobs go back to the top.
+type MLFQ struct {
+	mutex  sync.Mutex
+	levels []*queue
+	quanta []time.Duration
+	onDrop func(job *MLFQJob)
+	jobs   map[*MLFQJob]struct{} //jobs taken and not yet acked, nacked or timed out
+	wake   chan struct{}         //closed to wake waiters (nil if none)
+	stop   chan struct{}         //closed on close
+	closed bool
+}
+
+//NewMLFQ returns a new multi-level feedback queue (call Close)
+func NewMLFQ(config MLFQConfig) (m *MLFQ) {
+	if config.Levels <= 0 {
+		config.Levels = DefaultMLFQLevels
+	}
+	m = &MLFQ{onDrop: config.OnDrop, jobs: make(map[*MLFQJob]struct{}), stop: make(chan struct{})}
+	for level := 0; level < config.Levels; level++ {
+		m.levels = append(m.levels, NewQueue(config.Size, true).(*queue))
+		//Quanta
+		quantum := DefaultMLFQQuantum
+		switch {
+		case level < len(config.Quanta) && config.Quanta[level] > 0:
+			quantum = config.Quanta[level]
+		case level > 0:
+			quantum = 2 * m.quanta[level-1]
+		}
+		m.quanta = append(m.quanta, quantum)
+	}
+	if config.Boost > 0 {
+		go m.boosting(config.Boost)
+	}
+	return
+}
+
+//Enqueue will enqueue a single job in the top level
+func (m *MLFQ) Enqueue(element interface{}, priority int) (overflow bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		overflow = true
+		return
+	}
+	if overflow = m.levels[0].EnqueuePriority(element, priority); !overflow {
+		m.wakeup()
+	}
+	return
+}
+
+//Dequeue will take the next job (highest level, then highest priority)
+func (m *MLFQ) Dequeue() (job *MLFQJob, underflow bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job, underflow = m.dequeue()
+	return
+}
+
+//DequeueWait will wait for and take the next job
+//It waits until ctx is done (ctx.Err()) or the MLFQ is closed (ErrClosed).
+func (m *MLFQ) DequeueWait(ctx context.Context) (job *MLFQJob, err error) {
+	for {
+		m.mutex.Lock()
+		if m.closed {
+			m.mutex.Unlock()
+			err = ErrClosed
+			return
+		}
+		var underflow bool
+		if job, underflow = m.dequeue(); !underflow {
+			m.mutex.Unlock()
+			return
+		}
+		if m.wake == nil {
+			m.wake = make(chan struct{})
+		}
+		wake := m.wake
+		m.mutex.Unlock()
+		if err = wait(ctx, wake, 0); err != nil {
+			return
+		}
+	}
+}
+
+//GetLength will return the number of jobs waiting (not those taken)
+func (m *MLFQ) GetLength() (len int) {
+	for _, level := range m.GetLevels() {
+		len += level
+	}
+	return
+}
+
+//GetLevels will return the number of jobs waiting in each level
+func (m *MLFQ) GetLevels() (lengths []int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, level := range m.levels {
+		lengths = append(lengths, level.GetLength())
+	}
+	return
+}
+
+//Close will stop boosting, forget the jobs taken and close the levels
+func (m *MLFQ) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	close(m.stop)
+	for job := range m.jobs {
+		job.timer.Stop()
+	}
+	m.jobs = nil
+	for _, level := range m.levels {
+		level.Close()
+	}
+	m.wakeup()
+}
+
+//Ack will finish a job, reporting false if it was already finished or timed out
+func (j *MLFQJob) Ack() (ok bool) {
+	j.mlfq.mutex.Lock()
+	defer j.mlfq.mutex.Unlock()
+	ok = j.mlfq.finish(j)
+	return
+}
+
+//Nack will put a job back a level lower, reporting false if it was already finished or timed out
+//(or there is no room for it, when it is dropped).
+func (j *MLFQJob) Nack() (ok bool) {
+	j.mlfq.mutex.Lock()
+	finished := j.mlfq.finish(j)
+	ok = finished && j.mlfq.demote(j)
+	j.mlfq.mutex.Unlock()
+	if finished && !ok {
+		j.mlfq.drop(j)
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//dequeue will take the next job (locked)
+func (m *MLFQ) dequeue() (job *MLFQJob, underflow bool) {
+	underflow = true
+	if m.closed {
+		return
+	}
+	for index, level := range m.levels {
+		element, priority, meta, empty := level.DequeueWithMeta()
+		if empty {
+			continue
+		}
+		job = &MLFQJob{
+			Element:  element,
+			Priority: priority,
+			Meta:     meta,
+			Level:    index,
+			Deadline: time.Now().Add(m.quanta[index]),
+			mlfq:     m,
+		}
+		job.timer = time.AfterFunc(m.quanta[index], func() { m.timeout(job) })
+		m.jobs[job] = struct{}{}
+		underflow = false
+		return
+	}
+	return
+}
+
+//finish will forget a job taken, reporting false if it was not (locked)
+func (m *MLFQ) finish(job *MLFQJob) (ok bool) {
+	if _, ok = m.jobs[job]; ok {
+		job.timer.Stop()
+		delete(m.jobs, job)
+	}
+	return
+}
+
+//demote will put a job back a level lower (the bottom level stays put), reporting false if that
+//level is full (locked)
+func (m *MLFQ) demote(job *MLFQJob) (ok bool) {
+	level := job.Level + 1
+	if level >= len(m.levels) {
+		level = len(m.levels) - 1
+	}
+	if ok = !m.levels[level].EnqueueWithMeta(job.Element, job.Priority, job.Meta); ok {
+		m.wakeup()
+	}
+	return
+}
+
+//timeout will demote a job out of time
+func (m *MLFQ) timeout(job *MLFQJob) {
+	m.mutex.Lock()
+	dropped := m.finish(job) && !m.demote(job)
+	m.mutex.Unlock()
+	if dropped {
+		m.drop(job)
+	}
+}
+
+//drop will report a job there was no room for (unlocked)
+func (m *MLFQ) drop(job *MLFQJob) {
+	if m.onDrop != nil {
+		m.onDrop(job)
+	}
+}
+
+//boosting will boost every interval until closed
+func (m *MLFQ) boosting(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.boost()
+		}
+	}
+}
+
+//boost will move every job waiting back to the top level (while it has room)
+func (m *MLFQ) boost() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return
+	}
+	top := m.levels[0]
+	for _, level := range m.levels[1:] {
+		for top.GetLength() < top.GetSize() {
+			element, priority, meta, empty := level.DequeueWithMeta()
+			if empty {
+				break
+			}
+			//Moving is not an attempt
+			meta.Attempts--
+			top.EnqueueWithMeta(element, priority, meta)
+		}
+	}
+	m.wakeup()
+}
+
+//wakeup will wake everything waiting on the MLFQ (locked)
+func (m *MLFQ) wakeup() {
+	if m.wake != nil {
+		close(m.wake)
+		m.wake = nil
+	}
+}
@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// MLFQ
+//---------------------------------------------------------------------------------------------------
+
+//TestMLFQ will test jobs moving between levels
+func TestMLFQ(t *testing.T) {
+	const name string = "MLFQ"
+	cases := map[string]struct {
+		iConfig   MLFQConfig
+		iFinish   string        //"ack", "nack" or "" (left to time out)
+		iTimes    int           //times taken and finished
+		iWait     time.Duration //wait after
+		oLevels   []int
+		oAttempts int
+	}{
+		"Ack": {
+			iFinish:   "ack",
+			iTimes:    1,
+			oLevels:   []int{0, 0, 0},
+			oAttempts: 0,
+		},
+		"Nack": {
+			iFinish:   "nack",
+			iTimes:    1,
+			oLevels:   []int{0, 1, 0},
+			oAttempts: 1,
+		},
+		"Nack_Bottom": {
+			iFinish:   "nack",
+			iTimes:    4,
+			oLevels:   []int{0, 0, 1},
+			oAttempts: 4,
+		},
+		"Timeout": {
+			iConfig:   MLFQConfig{Quanta: []time.Duration{10 * time.Millisecond}},
+			iTimes:    1,
+			iWait:     50 * time.Millisecond,
+			oLevels:   []int{0, 1, 0},
+			oAttempts: 1,
+		},
+		"Boost": {
+			iConfig:   MLFQConfig{Boost: 20 * time.Millisecond},
+			iFinish:   "nack",
+			iTimes:    2,
+			iWait:     60 * time.Millisecond,
+			oLevels:   []int{1, 0, 0},
+			oAttempts: 2,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create MLFQ
+		testMLFQ := NewMLFQ(c.iConfig)
+		testMLFQ.Enqueue("job", 0)
+		for i := 0; i < c.iTimes; i++ {
+			job, underflow := testMLFQ.Dequeue()
+			if !assert.False(t, underflow, fmt.Sprintf("%s :Underflow", msg)) {
+				break
+			}
+			switch c.iFinish {
+			case "ack":
+				assert.True(t, job.Ack(), fmt.Sprintf("%s :Ack", msg))
+				assert.False(t, job.Ack(), fmt.Sprintf("%s :Ack_Twice", msg))
+			case "nack":
+				assert.True(t, job.Nack(), fmt.Sprintf("%s :Nack", msg))
+			}
+		}
+		time.Sleep(c.iWait)
+		//Assert
+		assert.Equal(t, c.oLevels, testMLFQ.GetLevels(), fmt.Sprintf("%s :Levels", msg))
+		if c.oAttempts > 0 {
+			job, _ := testMLFQ.Dequeue()
+			assert.Equal(t, c.oAttempts+1, job.Meta.Attempts, fmt.Sprintf("%s :Attempts", msg))
+		}
+		testMLFQ.Close()
+	}
+}
+
+//TestMLFQOrder will test jobs are taken top level first
+func TestMLFQOrder(t *testing.T) {
+	const name string = "MLFQOrder"
+	testMLFQ := NewMLFQ(MLFQConfig{Levels: 2, Size: 4})
+	defer testMLFQ.Close()
+	testMLFQ.Enqueue("demoted", 9)
+	job, _ := testMLFQ.Dequeue()
+	job.Nack()
+	testMLFQ.Enqueue("low", 0)
+	testMLFQ.Enqueue("high", 1)
+	//Take
+	var elements []interface{}
+	var levels []int
+	for {
+		job, underflow := testMLFQ.Dequeue()
+		if underflow {
+			break
+		}
+		elements = append(elements, job.Element)
+		levels = append(levels, job.Level)
+		job.Ack()
+	}
+	assert.Equal(t, []interface{}{"high", "low", "demoted"}, elements, fmt.Sprintf("%s :Elements", name))
+	assert.Equal(t, []int{0, 0, 1}, levels, fmt.Sprintf("%s :Levels", name))
+	//Wait
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		testMLFQ.Enqueue("later", 0)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	job, err := testMLFQ.DequeueWait(ctx)
+	assert.Nil(t, err, fmt.Sprintf("%s :Error", name))
+	assert.Equal(t, "later", job.Element, fmt.Sprintf("%s :Later", name))
+}
+
+//TestMLFQDrop will test jobs the level below has no room for are dropped and reported
+func TestMLFQDrop(t *testing.T) {
+	const name string = "MLFQDrop"
+	dropped := make(chan interface{}, 2)
+	testMLFQ := NewMLFQ(MLFQConfig{
+		Levels: 2,
+		Size:   1,
+		Quanta: []time.Duration{50 * time.Millisecond, time.Minute},
+		OnDrop: func(job *MLFQJob) { dropped <- job.Element },
+	})
+	defer testMLFQ.Close()
+	//Fill the bottom level
+	testMLFQ.Enqueue("a", 0)
+	job, _ := testMLFQ.Dequeue()
+	assert.True(t, job.Nack(), fmt.Sprintf("%s :Nack", name))
+	//Nacked
+	testMLFQ.Enqueue("b", 0)
+	job, _ = testMLFQ.Dequeue()
+	assert.False(t, job.Nack(), fmt.Sprintf("%s :Nack_Full", name))
+	//Out of time
+	testMLFQ.Enqueue("c", 0)
+	testMLFQ.Dequeue()
+	//Assert
+	var elements []interface{}
+	for len(elements) < 2 {
+		select {
+		case element := <-dropped:
+			elements = append(elements, element)
+		case <-time.After(time.Second):
+			t.Fatalf("%s :Timeout", name)
+		}
+	}
+	assert.Equal(t, []interface{}{"b", "c"}, elements, fmt.Sprintf("%s :Dropped", name))
+	assert.Equal(t, []int{0, 1}, testMLFQ.GetLevels(), fmt.Sprintf("%s :Levels", name))
+}
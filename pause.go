@@ -0,0 +1,58 @@
+package queue
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Pause provides methods of holding back delivery while producers keep enqueuing
+type Pause interface {
+	//Pause will stop handing out elements
+	Pause()
+	//Resume will hand out elements again
+	Resume()
+	//GetPaused returns true if paused
+	GetPaused() (paused bool)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Pause Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Pause will stop handing out elements
+//While paused Dequeue reports underflow (without counting it) and the signal is held back; Enqueue,
+//Peek and the rest carry on.
+func (q *queue) Pause() {
+	q.Lock()
+	defer q.Unlock()
+	if q.paused {
+		return
+	}
+	q.paused = true
+	q.publish(Paused, nil)
+}
+
+//Resume will hand out elements again
+//The signals held back fire once each (as far as the signal channel has room).
+func (q *queue) Resume() {
+	q.Lock()
+	defer q.Unlock()
+	if !q.paused {
+		return
+	}
+	q.paused = false
+	held := q.held
+	q.held = 0
+	for ; held > 0; held-- {
+		q.triggerSignal()
+	}
+	q.publish(Resumed, nil)
+	q.wakeup()
+}
+
+//GetPaused returns true if paused
+func (q *queue) GetPaused() (paused bool) {
+	q.Lock()
+	defer q.Unlock()
+	paused = q.paused
+	return
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Pause
+//---------------------------------------------------------------------------------------------------
+
+//TestPause will test holding back delivery and signals
+func TestPause(t *testing.T) {
+	const name string = "Pause"
+	cases := map[string]struct {
+		iBefore   int //enqueued before pausing
+		iDuring   int //enqueued while paused
+		iResume   bool
+		oSignals  int
+		oElements []interface{}
+	}{
+		"Paused": {
+			iBefore:  1,
+			iDuring:  2,
+			oSignals: 1,
+		},
+		"Resumed": {
+			iBefore:   1,
+			iDuring:   2,
+			iResume:   true,
+			oSignals:  3,
+			oElements: []interface{}{0, 1, 2},
+		},
+		"Resumed_Nothing_Held": {
+			iBefore:   2,
+			iResume:   true,
+			oSignals:  2,
+			oElements: []interface{}{0, 1},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(5, false)
+		element := 0
+		for i := 0; i < c.iBefore; i++ {
+			testQueue.Enqueue(element)
+			element++
+		}
+		testQueue.Pause()
+		testQueue.Pause()
+		for i := 0; i < c.iDuring; i++ {
+			testQueue.Enqueue(element)
+			element++
+		}
+		//Nothing handed out while paused
+		_, underflow := testQueue.Dequeue()
+		assert.True(t, underflow, fmt.Sprintf("%s :Underflow", msg))
+		assert.Equal(t, uint64(0), testQueue.Stats().Underflows, fmt.Sprintf("%s :Underflows", msg))
+		assert.Equal(t, c.iBefore+c.iDuring, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+		if c.iResume {
+			testQueue.Resume()
+		}
+		assert.Equal(t, !c.iResume, testQueue.GetPaused(), fmt.Sprintf("%s :Paused", msg))
+		//Count the signals
+		signals := 0
+		for len(testQueue.GetSignal()) > 0 {
+			<-testQueue.GetSignal()
+			signals++
+		}
+		//Dequeue
+		var elements []interface{}
+		for {
+			element, underflow := testQueue.Dequeue()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+		}
+		//Assert
+		assert.Equal(t, c.oSignals, signals, fmt.Sprintf("%s :Signals", msg))
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		testQueue.Close()
+	}
+}
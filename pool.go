@@ -0,0 +1,373 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultWorkers is the default number of pool workers
+	DefaultWorkers int = 1
+	//DefaultPollInterval is how long an idle worker waits before looking again without a signal
+	DefaultPollInterval time.Duration = 100 * time.Millisecond
+)
+
+var (
+	//ErrPoolStarted is returned when a pool is started twice
+	ErrPoolStarted = errors.New("queue: pool already started")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//Handler handles a single element
+//With Ack on, an error (or panic) is a nack and the element goes back in the queue.
+type Handler func(ctx context.Context, element interface{}, priority int, meta Meta) (err error)
+
+//PoolQueue is what a pool needs of a queue
+type PoolQueue interface {
+	Event
+	Metadata
+}
+
+//PoolConfig is the config of a pool
+type PoolConfig struct {
+	Workers     int           //workers (0 is DefaultWorkers, the starting number when scaling)
+	Poll        time.Duration //idle wait without a signal (0 is DefaultPollInterval)
+	Ack         bool          //nack (handler error or panic) puts the element back
+	MaxAttempts int           //with Ack, attempts before giving up on an element (0 is no limit)
+	//OnPanic is called when a handler panics
+	OnPanic func(element interface{}, priority int, recovered interface{})
+	//OnError is called when a handler fails and the element is not put back
+	OnError func(element interface{}, priority int, meta Meta, err error)
+	//Scale grows and shrinks the workers (nil is a fixed number)
+	Scale *ScaleConfig
+	//Bands reserve or cap workers by priority (an element takes the first band it falls in)
+	Bands []Band
+}
+
+//Band is a range of priorities with reserved or capped workers
+type Band struct {
+	Low      int //lowest priority (inclusive, math.MinInt for no bound)
+	High     int //highest priority (inclusive, math.MaxInt for no bound)
+	Reserved int //workers always kept free for the band
+	Limit    int //most workers on the band at once (0 is no limit)
+}
+
+//PanicError is the error of a handler that panicked
+type PanicError struct {
+	Recovered interface{} //what was recovered
+}
+
+//Error implements error
+func (e *PanicError) Error() string {
+	return "queue: handler panicked"
+}
+
+//---------------------------------------------------------------------------------------------------
+// Pool
+//---------------------------------------------------------------------------------------------------
+
+//Pool consumes a queue with a number of workers
+type Pool struct {
+	queue   PoolQueue
+	handler Handler
+	config  PoolConfig
+	mutex   sync.Mutex
+	started bool
+	stop    chan struct{}      //closed to stop taking work
+	cancel  context.CancelFunc //cancels the handlers
+	workers sync.WaitGroup
+	quits   []chan struct{} //one per worker taking work (closed to retire it)
+	running int             //workers not yet exited (taking work or finishing an element)
+	busy    busy            //handler time since the last scaling decision
+	bands   bands           //workers on each band
+}
+
+//NewPool returns a new pool (call Start)
+func NewPool(q PoolQueue, handler Handler, config PoolConfig) (p *Pool) {
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.Poll <= 0 {
+		config.Poll = DefaultPollInterval
+	}
+	if config.Scale != nil {
+		scale := config.Scale.defaults()
+		config.Scale = &scale
+		config.Workers = scale.clamp(config.Workers)
+	}
+	p = &Pool{queue: q, handler: handler, config: config, stop: make(chan struct{})}
+	p.bands.running = make([]int, len(config.Bands))
+	return
+}
+
+//Start will start the workers
+//Cancelling ctx stops the workers and cancels the handlers' context without waiting; use Shutdown
+//to let the handlers finish.
+func (p *Pool) Start(ctx context.Context) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.started {
+		err = ErrPoolStarted
+		return
+	}
+	p.started = true
+	ctx, p.cancel = context.WithCancel(ctx)
+	go func() {
+		<-ctx.Done()
+		p.halt()
+	}()
+	p.resize(ctx, p.config.Workers)
+	if p.config.Scale != nil {
+		go p.autoscale(ctx)
+	}
+	return
+}
+
+//GetWorkers returns the number of workers running
+//Workers stopped or retired count until they have finished their element.
+func (p *Pool) GetWorkers() (workers int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	workers = p.running
+	return
+}
+
+//Shutdown will stop taking work and wait for the handlers in flight
+//If ctx is done first the handlers' context is cancelled and ctx.Err() returned.
+func (p *Pool) Shutdown(ctx context.Context) (err error) {
+	p.halt()
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	p.mutex.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.mutex.Unlock()
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//contextDequeuer is a queue that can dequeue with both metadata and the carried context, and put
+//an element back with what it carried
+type contextDequeuer interface {
+	dequeueContext(parent context.Context) (ctx context.Context, cancel context.CancelFunc, element interface{}, priority int, meta Meta, carried *carried, underflow bool)
+	requeue(element interface{}, priority int, meta Meta, carried *carried) (overflow bool)
+}
+
+//taking returns the number of workers taking new work
+func (p *Pool) taking() (workers int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	workers = len(p.quits)
+	return
+}
+
+//halt will stop taking work
+func (p *Pool) halt() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+		p.quits = nil
+	}
+}
+
+//resize will start or retire workers to reach a number (locked)
+func (p *Pool) resize(ctx context.Context, workers int) {
+	for len(p.quits) < workers {
+		quit := make(chan struct{})
+		p.quits = append(p.quits, quit)
+		p.running++
+		p.workers.Add(1)
+		go p.work(ctx, quit)
+	}
+	for len(p.quits) > workers {
+		//A retired worker finishes its element first
+		close(p.quits[len(p.quits)-1])
+		p.quits = p.quits[:len(p.quits)-1]
+	}
+}
+
+//work is a single worker
+func (p *Pool) work(ctx context.Context, quit chan struct{}) {
+	defer func() {
+		p.mutex.Lock()
+		p.running--
+		p.mutex.Unlock()
+		p.workers.Done()
+	}()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-quit:
+			return
+		default:
+		}
+		if !p.next(ctx) {
+			p.idle(quit)
+		}
+	}
+}
+
+//idle will wait for a signal, the poll interval, retirement or stop
+func (p *Pool) idle(quit chan struct{}) {
+	timer := time.NewTimer(p.config.Poll)
+	defer timer.Stop()
+	select {
+	case <-p.queue.GetSignal():
+	case <-timer.C:
+	case <-quit:
+	case <-p.stop:
+	}
+}
+
+//next will take and handle a single element, reporting if there was one
+func (p *Pool) next(ctx context.Context) (ok bool) {
+	var cancel context.CancelFunc
+	var element interface{}
+	var priority int
+	var meta Meta
+	var carried *carried
+	var underflow bool
+	//With bands the head is only taken if its band allows
+	if len(p.config.Bands) > 0 {
+		p.bands.Lock()
+		if _, head, _, empty := p.queue.PeekWithMeta(); empty || !p.admit(head) {
+			p.bands.Unlock()
+			return
+		}
+	}
+	if dequeuer, is := p.queue.(contextDequeuer); is {
+		ctx, cancel, element, priority, meta, carried, underflow = dequeuer.dequeueContext(ctx)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+		element, priority, meta, underflow = p.queue.DequeueWithMeta()
+	}
+	defer cancel()
+	if len(p.config.Bands) > 0 {
+		if !underflow {
+			defer p.leave(p.enter(priority))
+		}
+		p.bands.Unlock()
+	}
+	if underflow {
+		return
+	}
+	ok = true
+	start := time.Now()
+	err := p.handle(ctx, element, priority, meta)
+	p.busy.add(time.Since(start))
+	if err != nil {
+		p.fail(element, priority, meta, carried, err)
+	}
+	return
+}
+
+//handle will run the handler, turning a panic into an error
+func (p *Pool) handle(ctx context.Context, element interface{}, priority int, meta Meta) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if p.config.OnPanic != nil {
+				p.config.OnPanic(element, priority, recovered)
+			}
+			err = &PanicError{Recovered: recovered}
+		}
+	}()
+	err = p.handler(ctx, element, priority, meta)
+	return
+}
+
+//fail will nack (put back, with what it carried) or report a failed element
+func (p *Pool) fail(element interface{}, priority int, meta Meta, carried *carried, err error) {
+	if p.config.Ack && (p.config.MaxAttempts <= 0 || meta.Attempts < p.config.MaxAttempts) {
+		var overflow bool
+		if dequeuer, is := p.queue.(contextDequeuer); is {
+			overflow = dequeuer.requeue(element, priority, meta, carried)
+		} else {
+			overflow = p.queue.EnqueueWithMeta(element, priority, meta)
+		}
+		if !overflow {
+			return
+		}
+	}
+	if p.config.OnError != nil {
+		p.config.OnError(element, priority, meta, err)
+	}
+}
+
+//bands counts the workers on each band
+type bands struct {
+	sync.Mutex
+	running []int //workers on each band
+	total   int   //workers on any element
+}
+
+//band returns the band of a priority (-1 if none)
+func (p *Pool) band(priority int) (index int) {
+	for index = range p.config.Bands {
+		if band := p.config.Bands[index]; priority >= band.Low && priority <= band.High {
+			return
+		}
+	}
+	index = -1
+	return
+}
+
+//admit reports if a worker may take an element of a priority (bands locked)
+//It may if its band is under its limit and, once taken, enough workers stay free for the
+//reservations of the other bands.
+func (p *Pool) admit(priority int) (ok bool) {
+	index := p.band(priority)
+	if index >= 0 {
+		if limit := p.config.Bands[index].Limit; limit > 0 && p.bands.running[index] >= limit {
+			return
+		}
+	}
+	free := p.taking() - p.bands.total
+	for other, band := range p.config.Bands {
+		if other != index && band.Reserved > p.bands.running[other] {
+			free -= band.Reserved - p.bands.running[other]
+		}
+	}
+	ok = free >= 1
+	return
+}
+
+//enter counts a worker on the band of a priority (bands locked)
+func (p *Pool) enter(priority int) (index int) {
+	index = p.band(priority)
+	if index >= 0 {
+		p.bands.running[index]++
+	}
+	p.bands.total++
+	return
+}
+
+//leave uncounts a worker from a band
+func (p *Pool) leave(index int) {
+	p.bands.Lock()
+	defer p.bands.Unlock()
+	if index >= 0 {
+		p.bands.running[index]--
+	}
+	p.bands.total--
+}
@@ -0,0 +1,306 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Pool
+//---------------------------------------------------------------------------------------------------
+
+//TestPool will test handling, nack and panics
+func TestPool(t *testing.T) {
+	const name string = "Pool"
+	errFail := errors.New("fail")
+	cases := map[string]struct {
+		iConfig   PoolConfig
+		iFailures int  //attempts that fail before one succeeds (-1 for all)
+		iPanic    bool //fail by panicking
+		oHandled  int  //handler calls
+		oErrors   []int
+		oPanics   int
+	}{
+		"Handle": {
+			iConfig:  PoolConfig{Workers: 3},
+			oHandled: 5,
+		},
+		"No_Ack": {
+			iConfig:   PoolConfig{Workers: 2},
+			iFailures: 1,
+			oHandled:  5,
+			oErrors:   []int{1, 1, 1, 1, 1},
+		},
+		"Ack_Retry": {
+			iConfig:   PoolConfig{Workers: 2, Ack: true},
+			iFailures: 2,
+			oHandled:  15,
+		},
+		"Ack_Max_Attempts": {
+			iConfig:   PoolConfig{Workers: 2, Ack: true, MaxAttempts: 2},
+			iFailures: -1,
+			oHandled:  10,
+			oErrors:   []int{2, 2, 2, 2, 2},
+		},
+		"Panic": {
+			iConfig:   PoolConfig{Workers: 1, Ack: true, MaxAttempts: 1},
+			iFailures: -1,
+			iPanic:    true,
+			oHandled:  5,
+			oErrors:   []int{1, 1, 1, 1, 1},
+			oPanics:   5,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(10, false)
+		for i := 0; i < 5; i++ {
+			testQueue.Enqueue(i)
+		}
+		//Pool
+		var mutex sync.Mutex
+		handled, panics := 0, 0
+		var errs []int
+		done := make(chan struct{}, 100)
+		config := c.iConfig
+		config.Poll = time.Millisecond
+		config.OnPanic = func(element interface{}, priority int, recovered interface{}) {
+			mutex.Lock()
+			panics++
+			mutex.Unlock()
+		}
+		config.OnError = func(element interface{}, priority int, meta Meta, err error) {
+			mutex.Lock()
+			errs = append(errs, meta.Attempts)
+			mutex.Unlock()
+			done <- struct{}{}
+		}
+		pool := NewPool(testQueue, func(ctx context.Context, element interface{}, priority int, meta Meta) error {
+			mutex.Lock()
+			handled++
+			mutex.Unlock()
+			if c.iFailures < 0 || meta.Attempts <= c.iFailures {
+				if !c.iPanic {
+					return errFail
+				}
+				panic("boom")
+			}
+			done <- struct{}{}
+			return nil
+		}, config)
+		if err := pool.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		//Wait for every element to finish (handled or given up on)
+		for i := 0; i < 5; i++ {
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatalf("%s :timed out", msg)
+			}
+		}
+		assert.NoError(t, pool.Shutdown(context.Background()), fmt.Sprintf("%s :Shutdown", msg))
+		//Assert
+		assert.Equal(t, c.oHandled, handled, fmt.Sprintf("%s :Handled", msg))
+		assert.Equal(t, c.oErrors, errs, fmt.Sprintf("%s :Errors", msg))
+		assert.Equal(t, c.oPanics, panics, fmt.Sprintf("%s :Panics", msg))
+		assert.Equal(t, 0, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+		testQueue.Close()
+	}
+}
+
+//TestPoolShutdown will test waiting for (and giving up on) handlers in flight
+func TestPoolShutdown(t *testing.T) {
+	const name string = "PoolShutdown"
+	cases := map[string]struct {
+		iTimeout  time.Duration
+		iRelease  bool
+		oErr      error
+		oCanceled bool
+	}{
+		"Graceful": {
+			iTimeout: time.Second,
+			iRelease: true,
+		},
+		"Timeout": {
+			iTimeout:  10 * time.Millisecond,
+			oErr:      context.DeadlineExceeded,
+			oCanceled: true,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(2, false)
+		testQueue.Enqueue(1)
+		//Pool with a handler that waits to be released (or cancelled)
+		started, release, finished := make(chan struct{}), make(chan struct{}), make(chan error, 1)
+		pool := NewPool(testQueue, func(ctx context.Context, element interface{}, priority int, meta Meta) error {
+			close(started)
+			select {
+			case <-release:
+				finished <- nil
+			case <-ctx.Done():
+				finished <- ctx.Err()
+			}
+			return nil
+		}, PoolConfig{Poll: time.Millisecond})
+		pool.Start(context.Background())
+		<-started
+		//Still running while shutting down, until released
+		running := make(chan int, 1)
+		if c.iRelease {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				running <- pool.GetWorkers()
+				close(release)
+			}()
+		}
+		//Shutdown
+		ctx, cancel := context.WithTimeout(context.Background(), c.iTimeout)
+		err := pool.Shutdown(ctx)
+		cancel()
+		//Assert
+		assert.Equal(t, c.oErr, err, fmt.Sprintf("%s :Error", msg))
+		assert.Equal(t, c.oCanceled, <-finished != nil, fmt.Sprintf("%s :Canceled", msg))
+		if c.iRelease {
+			assert.Equal(t, 1, <-running, fmt.Sprintf("%s :Running", msg))
+			assert.Equal(t, 0, pool.GetWorkers(), fmt.Sprintf("%s :Exited", msg))
+		}
+		assert.Equal(t, ErrPoolStarted, pool.Start(context.Background()), fmt.Sprintf("%s :Restart", msg))
+		testQueue.Close()
+	}
+}
+
+//TestPoolRetryContext will test a nacked element keeps what it carried on the next attempt
+func TestPoolRetryContext(t *testing.T) {
+	const name string = "PoolRetryContext"
+	traceKey := propagateKey("trace")
+	testQueue := NewQueue(2, false)
+	defer testQueue.Close()
+	testQueue.SetPropagation(PropagationConfig{Keys: []interface{}{traceKey}})
+	testQueue.EnqueueContext(context.WithValue(context.Background(), traceKey, "abc"), "job", 0)
+	//Fail the first attempt
+	values := make(chan interface{}, 2)
+	pool := NewPool(testQueue, func(ctx context.Context, element interface{}, priority int, meta Meta) error {
+		values <- ctx.Value(traceKey)
+		if meta.Attempts == 1 {
+			return errors.New("fail")
+		}
+		return nil
+	}, PoolConfig{Poll: time.Millisecond, Ack: true})
+	pool.Start(context.Background())
+	defer pool.Shutdown(context.Background())
+	//Assert
+	for attempt := 1; attempt <= 2; attempt++ {
+		select {
+		case value := <-values:
+			assert.Equal(t, "abc", value, fmt.Sprintf("%s :Attempt_%d", name, attempt))
+		case <-time.After(time.Second):
+			t.Fatalf("%s :Attempt_%d not handled", name, attempt)
+		}
+	}
+}
+
+//TestPoolBands will test reserving and capping workers by priority
+func TestPoolBands(t *testing.T) {
+	const name string = "PoolBands"
+	cases := map[string]struct {
+		iWorkers  int
+		iBands    []Band
+		iElements map[int]int //priority to count
+		oPeak     map[int]int //priority to most handled at once
+	}{
+		"No_Bands": {
+			iWorkers:  4,
+			iElements: map[int]int{0: 8},
+			oPeak:     map[int]int{0: 4},
+		},
+		"Limit": {
+			iWorkers:  4,
+			iBands:    []Band{{Low: math.MinInt, High: 0, Limit: 2}},
+			iElements: map[int]int{0: 8},
+			oPeak:     map[int]int{0: 2},
+		},
+		"Reserved": {
+			iWorkers:  3,
+			iBands:    []Band{{Low: 10, High: math.MaxInt, Reserved: 2}},
+			iElements: map[int]int{0: 6},
+			oPeak:     map[int]int{0: 1},
+		},
+		"Reserved_Used": {
+			iWorkers:  3,
+			iBands:    []Band{{Low: 10, High: math.MaxInt, Reserved: 2}},
+			iElements: map[int]int{10: 6},
+			oPeak:     map[int]int{10: 3},
+		},
+		"Both": {
+			iWorkers: 4,
+			iBands: []Band{
+				{Low: 10, High: math.MaxInt, Reserved: 2},
+				{Low: math.MinInt, High: 0, Limit: 1},
+			},
+			iElements: map[int]int{0: 4, 5: 4},
+			oPeak:     map[int]int{0: 1, 5: 2},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		total := 0
+		testQueue := NewQueue(32, false)
+		for priority, count := range c.iElements {
+			for i := 0; i < count; i++ {
+				testQueue.EnqueuePriority(i, priority)
+				total++
+			}
+		}
+		//Pool tracking how many run at once by priority
+		var mutex sync.Mutex
+		running, peak := map[int]int{}, map[int]int{}
+		done := make(chan struct{}, total)
+		pool := NewPool(testQueue, func(ctx context.Context, element interface{}, priority int, meta Meta) error {
+			mutex.Lock()
+			running[priority]++
+			if running[priority] > peak[priority] {
+				peak[priority] = running[priority]
+			}
+			mutex.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mutex.Lock()
+			running[priority]--
+			mutex.Unlock()
+			done <- struct{}{}
+			return nil
+		}, PoolConfig{Workers: c.iWorkers, Poll: time.Millisecond, Bands: c.iBands})
+		pool.Start(context.Background())
+		for i := 0; i < total; i++ {
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%s :timed out", msg)
+			}
+		}
+		pool.Shutdown(context.Background())
+		//Assert
+		assert.Equal(t, c.oPeak, peak, fmt.Sprintf("%s :Peak", msg))
+		testQueue.Close()
+	}
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+const (
+	//prometheusContentType is the content type of the text exposition format
+	prometheusContentType string = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+//labelEscaper escapes label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+//---------------------------------------------------------------------------------------------------
+// Prometheus
+//---------------------------------------------------------------------------------------------------
+
+//NewPrometheusHandler returns a handler rendering the stats of every queue in a registry in the
+//Prometheus text exposition format
+//Every series is labeled with the queue name; depth is also labeled with the priority.
+func NewPrometheusHandler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//Snapshot
+		var names []string
+		var stats []Stats
+		for _, name := range registry.Names() {
+			q, ok := registry.Get(name)
+			if !ok {
+				continue
+			}
+			names = append(names, name)
+			stats = append(stats, q.Stats())
+		}
+		//Render
+		var buffer bytes.Buffer
+		writePrometheus(&buffer, names, stats)
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.Write(buffer.Bytes())
+	})
+}
+
+//metric describes a single value per queue
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(s Stats) float64
+}
+
+//metrics are the single value series
+var metrics = []metric{
+	{"queue_size", "gauge", "Max size of the queue.", func(s Stats) float64 { return float64(s.Size) }},
+	{"queue_length", "gauge", "Current length of the queue.", func(s Stats) float64 { return float64(s.Length) }},
+	{"queue_spilled", "gauge", "Current length of the queue on disk.", func(s Stats) float64 { return float64(s.Spilled) }},
+	{"queue_enqueued_total", "counter", "Elements enqueued.", func(s Stats) float64 { return float64(s.Enqueued) }},
+	{"queue_dequeued_total", "counter", "Elements dequeued.", func(s Stats) float64 { return float64(s.Dequeued) }},
+	{"queue_overflows_total", "counter", "Enqueues refused as full.", func(s Stats) float64 { return float64(s.Overflows) }},
+	{"queue_underflows_total", "counter", "Dequeues attempted while empty.", func(s Stats) float64 { return float64(s.Underflows) }},
+	{"queue_flushed_total", "counter", "Elements removed by flush or resize.", func(s Stats) float64 { return float64(s.Flushed) }},
+	{"queue_expired_total", "counter", "Elements dropped for missing their deadline.", func(s Stats) float64 { return float64(s.Expired) }},
+	{"queue_missed_total", "counter", "Elements past their deadline.", func(s Stats) float64 { return float64(s.Missed) }},
+	{"queue_evicted_total", "counter", "Elements written to disk for lack of room in memory.", func(s Stats) float64 { return float64(s.Evicted) }},
+	{"queue_corrupted_total", "counter", "Spilled elements dropped as they could not be read back.", func(s Stats) float64 { return float64(s.Corrupted) }},
+	{"queue_oldest_age_seconds", "gauge", "How long the oldest element has waited.", func(s Stats) float64 { return s.OldestAge.Seconds() }},
+}
+
+//writePrometheus renders the stats of named queues
+func writePrometheus(buffer *bytes.Buffer, names []string, stats []Stats) {
+	//Single values
+	for _, m := range metrics {
+		fmt.Fprintf(buffer, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for index, s := range stats {
+			fmt.Fprintf(buffer, "%s{queue=\"%s\"} %s\n", m.name, labelEscaper.Replace(names[index]), formatFloat(m.value(s)))
+		}
+	}
+	//Depth by priority
+	buffer.WriteString("# HELP queue_depth Current length of the queue by priority.\n# TYPE queue_depth gauge\n")
+	for index, s := range stats {
+		priorities := make([]int, 0, len(s.Depth))
+		for priority := range s.Depth {
+			priorities = append(priorities, priority)
+		}
+		sort.Ints(priorities)
+		for _, priority := range priorities {
+			fmt.Fprintf(buffer, "queue_depth{queue=\"%s\",priority=\"%d\"} %d\n", labelEscaper.Replace(names[index]), priority, s.Depth[priority])
+		}
+	}
+	//Latency
+	buffer.WriteString("# HELP queue_latency_seconds Time from enqueue to dequeue.\n# TYPE queue_latency_seconds histogram\n")
+	for index, s := range stats {
+		name := labelEscaper.Replace(names[index])
+		var cumulative uint64
+		for i, bound := range s.Latency.Bounds {
+			if i < len(s.Latency.Counts) {
+				cumulative += s.Latency.Counts[i]
+			}
+			fmt.Fprintf(buffer, "queue_latency_seconds_bucket{queue=\"%s\",le=\"%s\"} %d\n", name, formatFloat(bound.Seconds()), cumulative)
+		}
+		fmt.Fprintf(buffer, "queue_latency_seconds_bucket{queue=\"%s\",le=\"+Inf\"} %d\n", name, s.Latency.Count)
+		fmt.Fprintf(buffer, "queue_latency_seconds_sum{queue=\"%s\"} %s\n", name, formatFloat(s.Latency.Sum.Seconds()))
+		fmt.Fprintf(buffer, "queue_latency_seconds_count{queue=\"%s\"} %d\n", name, s.Latency.Count)
+	}
+}
+
+//formatFloat formats a value for the exposition format
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
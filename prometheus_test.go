@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Prometheus
+//---------------------------------------------------------------------------------------------------
+
+//TestPrometheusHandler will test rendering the stats of named queues
+func TestPrometheusHandler(t *testing.T) {
+	const name string = "PrometheusHandler"
+	//Create Queues
+	urgent := NewQueue(4, true)
+	defer urgent.Close()
+	bulk := NewQueue(1, true)
+	defer bulk.Close()
+	urgent.EnqueuePriority(1, 10)
+	urgent.EnqueuePriority(2, 10)
+	urgent.EnqueuePriority(3, 0)
+	urgent.Dequeue()
+	bulk.Enqueue(1)
+	bulk.Enqueue(2)
+	//Register
+	registry := NewRegistry()
+	if err := registry.Register("urgent", urgent); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(`bulk "b"`, bulk); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrRegistered, registry.Register("urgent", bulk), fmt.Sprintf("%s :Registered", name))
+	//Scrape
+	recorder := httptest.NewRecorder()
+	NewPrometheusHandler(registry).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(recorder.Body)
+	lines := strings.Split(string(body), "\n")
+	//Assert
+	assert.Equal(t, prometheusContentType, recorder.Header().Get("Content-Type"), fmt.Sprintf("%s :Content-Type", name))
+	for _, line := range []string{
+		"# TYPE queue_length gauge",
+		`queue_length{queue="urgent"} 2`,
+		`queue_length{queue="bulk \"b\""} 1`,
+		`queue_overflows_total{queue="bulk \"b\""} 1`,
+		`queue_depth{queue="urgent",priority="0"} 1`,
+		`queue_depth{queue="urgent",priority="10"} 1`,
+		"# TYPE queue_latency_seconds histogram",
+		`queue_latency_seconds_bucket{queue="urgent",le="+Inf"} 1`,
+		`queue_latency_seconds_count{queue="urgent"} 1`,
+	} {
+		assert.Contains(t, lines, line, fmt.Sprintf("%s :Line", name))
+	}
+	//Unregister
+	registry.Unregister("urgent")
+	recorder = httptest.NewRecorder()
+	NewPrometheusHandler(registry).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.NotContains(t, recorder.Body.String(), `queue="urgent"`, fmt.Sprintf("%s :Unregistered", name))
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//PropagationConfig is what is carried from the enqueue context to the dequeue context
+type PropagationConfig struct {
+	Keys     []interface{} //context keys whose values are carried (trace ids, tenant...)
+	Deadline bool          //carry the deadline
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Propagate provides methods of carrying context values across the queue
+type Propagate interface {
+	//SetPropagation will set what is carried from the enqueue context
+	SetPropagation(config PropagationConfig)
+	//EnqueueContext will enqueue a single element with the values of a context
+	EnqueueContext(ctx context.Context, element interface{}, priority int) (overflow bool)
+	//DequeueContext will dequeue a single element with a context rebuilt from what was carried
+	DequeueContext(parent context.Context) (ctx context.Context, cancel context.CancelFunc, element interface{}, priority int, underflow bool)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Propagate Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetPropagation will set what is carried from the enqueue context
+//Only elements enqueued afterwards carry the new set.
+func (q *queue) SetPropagation(config PropagationConfig) {
+	q.Lock()
+	defer q.Unlock()
+	q.propagation = config
+}
+
+//EnqueueContext will enqueue a single element with the values of a context
+//The values are held in memory only, so an element left on disk by an earlier process comes back
+//without them.
+func (q *queue) EnqueueContext(ctx context.Context, element interface{}, priority int) (overflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Enqueue
+	overflow = q.enqueue(&container{element: element, priority: priority, carried: q.capture(ctx)})
+	//Trigger signal
+	q.triggerSignal()
+	return
+}
+
+//DequeueContext will dequeue a single element with a context rebuilt from what was carried
+//The context derives from parent (for cancellation) and cancel must be called once done with it.
+func (q *queue) DequeueContext(parent context.Context) (ctx context.Context, cancel context.CancelFunc, element interface{}, priority int, underflow bool) {
+	ctx, cancel, element, priority, _, _, underflow = q.dequeueContext(parent)
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//dequeueContext will dequeue a single element with its metadata, what it carried and rebuilt context
+func (q *queue) dequeueContext(parent context.Context) (ctx context.Context, cancel context.CancelFunc, element interface{}, priority int, meta Meta, carried *carried, underflow bool) {
+	q.Lock()
+	var head *container
+	underflow, element, priority, head = q.dequeue()
+	q.Unlock()
+	if !underflow {
+		carried, meta = head.carried, head.meta()
+	}
+	ctx, cancel = carried.rebuild(parent)
+	return
+}
+
+//carried is what an element carries from its enqueue context
+type carried struct {
+	keys     []interface{}
+	values   []interface{}
+	deadline time.Time
+}
+
+//capture will take the configured values from a context (locked)
+func (q *queue) capture(ctx context.Context) (c *carried) {
+	if ctx == nil {
+		return
+	}
+	c = &carried{}
+	for _, key := range q.propagation.Keys {
+		if value := ctx.Value(key); value != nil {
+			c.keys = append(c.keys, key)
+			c.values = append(c.values, value)
+		}
+	}
+	if q.propagation.Deadline {
+		c.deadline, _ = ctx.Deadline()
+	}
+	if len(c.keys) == 0 && c.deadline.IsZero() {
+		c = nil
+	}
+	return
+}
+
+//rebuild will derive a context carrying the values
+func (c *carried) rebuild(parent context.Context) (ctx context.Context, cancel context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if c == nil {
+		ctx, cancel = context.WithCancel(parent)
+		return
+	}
+	ctx = parent
+	for index, key := range c.keys {
+		ctx = context.WithValue(ctx, key, c.values[index])
+	}
+	if c.deadline.IsZero() {
+		ctx, cancel = context.WithCancel(ctx)
+		return
+	}
+	ctx, cancel = context.WithDeadline(ctx, c.deadline)
+	return
+}
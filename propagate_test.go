@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Propagate
+//---------------------------------------------------------------------------------------------------
+
+//propagateKey is a context key for the tests
+type propagateKey string
+
+//TestPropagate will test carrying context values across the queue
+func TestPropagate(t *testing.T) {
+	const name string = "Propagate"
+	deadline := time.Now().Add(time.Hour)
+	traceKey, tenantKey, otherKey := propagateKey("trace"), propagateKey("tenant"), propagateKey("other")
+	cases := map[string]struct {
+		iConfig    PropagationConfig
+		iValues    map[propagateKey]string
+		iDeadline  bool
+		oValues    map[propagateKey]interface{}
+		oDeadline  bool
+		oUnderflow bool
+	}{
+		"Keys": {
+			iConfig: PropagationConfig{Keys: []interface{}{traceKey, tenantKey}},
+			iValues: map[propagateKey]string{traceKey: "abc", tenantKey: "acme", otherKey: "x"},
+			oValues: map[propagateKey]interface{}{traceKey: "abc", tenantKey: "acme", otherKey: nil},
+		},
+		"Missing_Key": {
+			iConfig: PropagationConfig{Keys: []interface{}{traceKey, tenantKey}},
+			iValues: map[propagateKey]string{traceKey: "abc"},
+			oValues: map[propagateKey]interface{}{traceKey: "abc", tenantKey: nil},
+		},
+		"Deadline": {
+			iConfig:   PropagationConfig{Deadline: true},
+			iDeadline: true,
+			oDeadline: true,
+		},
+		"Deadline_Not_Carried": {
+			iConfig:   PropagationConfig{Keys: []interface{}{traceKey}},
+			iDeadline: true,
+		},
+		"Empty": {
+			oUnderflow: true,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(1, true)
+		testQueue.SetPropagation(c.iConfig)
+		//Build the enqueue context
+		ctx := context.Background()
+		for key, value := range c.iValues {
+			ctx = context.WithValue(ctx, key, value)
+		}
+		if c.iDeadline {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		if !c.oUnderflow {
+			testQueue.EnqueueContext(ctx, 1, 2)
+		}
+		//Dequeue
+		out, cancel, element, priority, underflow := testQueue.DequeueContext(context.Background())
+		//Assert
+		assert.Equal(t, c.oUnderflow, underflow, fmt.Sprintf("%s :Underflow", msg))
+		if !underflow {
+			assert.Equal(t, 1, element, fmt.Sprintf("%s :Element", msg))
+			assert.Equal(t, 2, priority, fmt.Sprintf("%s :Priority", msg))
+		}
+		for key, value := range c.oValues {
+			assert.Equal(t, value, out.Value(key), fmt.Sprintf("%s :Value %s", msg, key))
+		}
+		got, ok := out.Deadline()
+		assert.Equal(t, c.oDeadline, ok, fmt.Sprintf("%s :Deadline", msg))
+		if ok {
+			assert.True(t, got.Equal(deadline), fmt.Sprintf("%s :Deadline time", msg))
+		}
+		cancel()
+		assert.Error(t, out.Err(), fmt.Sprintf("%s :Cancel", msg))
+		testQueue.Close()
+	}
+}
@@ -3,6 +3,7 @@ package queue
 import (
 	"sort"
 	"sync"
+	"time"
 )
 
 //---------------------------------------------------------------------------------------------------
@@ -81,6 +82,14 @@ type EnqueuePriority interface {
 	EnqueuePriority(element interface{}, priority int) (overflow bool)
 }
 
+//Spill provides methods to spill elements beyond the size onto disk
+type Spill interface {
+	//Spill enables spilling elements that do not fit in memory to disk
+	Spill(config SpillConfig) (err error)
+	//GetSpilled will return the number of elements currently on disk
+	GetSpilled() (spilled int)
+}
+
 //---------------------------------------------------------------------------------------------------
 // Implementation
 //---------------------------------------------------------------------------------------------------
@@ -96,6 +105,19 @@ var _ Dequeue = &queue{}
 var _ DequeuePriority = &queue{}
 var _ Enqueue = &queue{}
 var _ EnqueuePriority = &queue{}
+var _ Spill = &queue{}
+var _ Statistics = &queue{}
+var _ Hooks = &queue{}
+var _ Subscribe = &queue{}
+var _ Watermarks = &queue{}
+var _ Logging = &queue{}
+var _ Propagate = &queue{}
+var _ Metadata = &queue{}
+var _ Remove = &queue{}
+var _ Pause = &queue{}
+var _ RateLimit = &queue{}
+var _ Aging = &queue{}
+var _ Deadline = &queue{}
 
 //HAHAHAAAHAHA (The interface is named Interface...)
 var _ sort.Interface = &queue{}
@@ -112,6 +134,19 @@ func NewQueue(size int, polling bool) interface {
 	DequeuePriority
 	Enqueue
 	EnqueuePriority
+	Spill
+	Statistics
+	Hooks
+	Subscribe
+	Watermarks
+	Logging
+	Propagate
+	Metadata
+	Remove
+	Pause
+	RateLimit
+	Aging
+	Deadline
 } {
 	//Check if size is valid
 	if size <= 0 {
@@ -130,16 +165,31 @@ func NewQueue(size int, polling bool) interface {
 		signal:     signal,
 		polling:    polling,
 		containers: containers,
+		counters:   counters{latency: Histogram{Bounds: DefaultLatencyBounds}},
 	}
 }
 
 //queue provides a pointer implementation of Queue
 type queue struct {
 	sync.Mutex
-	containers []*container  //containers
-	size       int           //the max size of the queue
-	signal     chan struct{} //signal to notify that element has been enqueued
-	polling    bool          //Don't use signal if polling
+	containers    []*container  //containers
+	size          int           //the max size of the queue
+	signal        chan struct{} //signal to notify that element has been enqueued
+	polling       bool          //Don't use signal if polling
+	sequence      uint64        //the next sequence number
+	spill         *spill        //disk overflow (nil if not spilling)
+	counters      counters      //running statistics
+	hooks         map[HookKind][]Hook
+	subscriptions []*subscription   //subscribers to changes
+	watermarks    *watermarks       //backpressure (nil if not set)
+	logger        *logger           //logging (nil if not set)
+	propagation   PropagationConfig //what is carried from the enqueue context
+	paused        bool              //hold back delivery
+	held          int               //signals held back while paused
+	rates         *rates            //dequeue limits (nil if none)
+	wake          chan struct{}     //closed to wake waiters (nil if none)
+	aging         *aging            //priority aging (nil if none)
+	deadlines     *DeadlineConfig   //earliest deadline first (nil if not)
 }
 
 //---------------------------------------------------------------------------------------------------
@@ -150,6 +200,22 @@ type queue struct {
 func (q *queue) Close() {
 	q.Lock()
 	defer q.Unlock()
+	//Hooks
+	q.runHooks(OnClose, nil, 0)
+	//Last change, then let subscribers go
+	q.publish(Closed, nil)
+	q.wakeup()
+	for _, sub := range q.subscriptions {
+		sub.stop()
+		close(sub.changes)
+	}
+	q.subscriptions = nil
+	q.closeWatermarks()
+	//Close the spill (its segments stay on disk)
+	if q.spill != nil {
+		q.spill.close()
+		q.spill = nil
+	}
 	//Cleanup
 	q.signal, q.containers = nil, nil
 	q.size = 0
@@ -160,18 +226,16 @@ func (q *queue) Resize(size int) (elements []interface{}, priorities []int) {
 	q.Lock()
 	defer q.Unlock()
 	//Get the elements and priorities
-	for _, container := range q.containers {
-		elements = append(elements, container.element)
-		priorities = append(priorities, container.priority)
-	}
-	//Reset the containers
-	// q.containers = make([]container, q.size)
-	q.containers = make([]*container, 0)
+	elements, priorities = q.drain()
 	//Check if size is valid
 	if size <= 0 {
 		size = DefaultSize
 	}
 	q.size = size
+	q.watermark()
+	//Hooks
+	q.runHooks(OnResize, nil, 0)
+	q.publish(Resized, nil)
 	return
 }
 
@@ -184,14 +248,7 @@ func (q *queue) Flush() (elements []interface{}, priorities []int) {
 	q.Lock()
 	defer q.Unlock()
 	//Get the elements and priorities
-	for index, container := range q.containers {
-		elements = append(elements, container.element)
-		priorities = append(priorities, container.priority)
-		q.containers[index] = nil
-	}
-	//Reset the containers
-	// q.containers = make([]container, q.size)
-	q.containers = make([]*container, 0)
+	elements, priorities = q.drain()
 	return
 }
 
@@ -223,7 +280,7 @@ func (q *queue) GetSize() (size int) {
 func (q *queue) GetLength() (len int) {
 	q.Lock()
 	defer q.Unlock()
-	len = q.Len()
+	len = q.length()
 	return
 }
 
@@ -235,6 +292,7 @@ func (q *queue) GetLength() (len int) {
 func (q *queue) Peek() (elements []interface{}, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	len := q.Len()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
@@ -255,6 +313,7 @@ func (q *queue) Peek() (elements []interface{}, empty bool) {
 func (q *queue) PeekHead() (element interface{}, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
 		return
@@ -268,6 +327,7 @@ func (q *queue) PeekHead() (element interface{}, empty bool) {
 func (q *queue) PeekTail() (element interface{}, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
 		return
@@ -285,6 +345,7 @@ func (q *queue) PeekTail() (element interface{}, empty bool) {
 func (q *queue) PeekPriority() (elements []interface{}, priorities []int, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
 		return
@@ -307,6 +368,7 @@ func (q *queue) PeekPriority() (elements []interface{}, priorities []int, empty
 func (q *queue) PeekHeadPriority() (element interface{}, priority int, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
 		return
@@ -322,6 +384,7 @@ func (q *queue) PeekHeadPriority() (element interface{}, priority int, empty boo
 func (q *queue) PeekTailPriority() (element interface{}, priority int, empty bool) {
 	q.Lock()
 	defer q.Unlock()
+	q.age()
 	//Check if empty
 	if empty = q.checkIfEmpty(); empty {
 		return
@@ -344,7 +407,7 @@ func (q *queue) Dequeue() (element interface{}, underflow bool) {
 	q.Lock()
 	defer q.Unlock()
 	//Dequeue
-	underflow, element, _ = q.dequeue()
+	underflow, element, _, _ = q.dequeue()
 	return
 }
 
@@ -357,7 +420,7 @@ func (q *queue) DequeuePriority() (element interface{}, priority int, underflow
 	q.Lock()
 	defer q.Unlock()
 	//Dequeue
-	underflow, element, priority = q.dequeue()
+	underflow, element, priority, _ = q.dequeue()
 	return
 }
 
@@ -370,7 +433,7 @@ func (q *queue) Enqueue(element interface{}) (overflow bool) {
 	q.Lock()
 	defer q.Unlock()
 	//Enqueue
-	overflow = q.enqueue(element, DefaultPriority)
+	overflow = q.enqueue(&container{element: element, priority: DefaultPriority})
 	//Trigger signal
 	q.triggerSignal()
 	return
@@ -385,7 +448,7 @@ func (q *queue) EnqueuePriority(element interface{}, priority int) (overflow boo
 	q.Lock()
 	defer q.Unlock()
 	//Enqueue
-	overflow = q.enqueue(element, priority)
+	overflow = q.enqueue(&container{element: element, priority: priority})
 	//Trigger signal
 	q.triggerSignal()
 	return
@@ -401,6 +464,11 @@ func (q *queue) triggerSignal() {
 	if q.polling {
 		return
 	}
+	//Hold back while paused
+	if q.paused {
+		q.held++
+		return
+	}
 	//Fire
 	select {
 	case q.signal <- struct{}{}:
@@ -408,6 +476,63 @@ func (q *queue) triggerSignal() {
 	}
 }
 
+//nextSequence returns the next sequence number
+func (q *queue) nextSequence() (seq uint64) {
+	seq = q.sequence
+	q.sequence++
+	return
+}
+
+//length returns the length of the queue (memory and disk)
+func (q *queue) length() (len int) {
+	len = q.Len()
+	if q.spill != nil {
+		len += q.spill.Len()
+	}
+	return
+}
+
+//drain will empty the queue (memory and disk) and return what was in it
+func (q *queue) drain() (elements []interface{}, priorities []int) {
+	//Changes go out once the queue is empty
+	var drained []*container
+	defer func() {
+		q.watermark()
+		for _, container := range drained {
+			q.publish(Flushed, container)
+		}
+	}()
+	//Get the elements and priorities
+	for index, container := range q.containers {
+		elements = append(elements, container.element)
+		priorities = append(priorities, container.priority)
+		q.containers[index] = nil
+		q.counters.flushed++
+		q.runHooks(OnFlush, container.element, container.priority)
+		drained = append(drained, container)
+	}
+	//Reset the containers
+	// q.containers = make([]container, q.size)
+	q.containers = make([]*container, 0)
+	//Get what is on disk (already ordered behind memory)
+	if q.spill == nil {
+		return
+	}
+	for q.spill.Len() > 0 {
+		container, err := q.spill.pop()
+		if err != nil {
+			q.corrupt(container, err)
+			continue
+		}
+		elements = append(elements, container.element)
+		priorities = append(priorities, container.priority)
+		q.counters.flushed++
+		q.runHooks(OnFlush, container.element, container.priority)
+		drained = append(drained, container)
+	}
+	return
+}
+
 //checkIfEmpty will check if the queue is empty
 func (q *queue) checkIfEmpty() (empty bool) {
 	empty = q.Len() <= 0
@@ -420,33 +545,109 @@ func (q *queue) checkIfFull() (full bool) {
 	return
 }
 
-//enqueue performs the enqueue logic
-func (q *queue) enqueue(element interface{}, priority int) (overflow bool) {
-	//Check if queue is full (overflow)
-	if q.checkIfFull() {
+//enqueue performs the enqueue logic (the sequence and enqueue time are set here)
+func (q *queue) enqueue(incoming *container) (overflow bool) {
+	//Hooks may change or reject the element
+	event, err := q.runHooks(BeforeEnqueue, incoming.element, incoming.priority)
+	if err != nil {
 		overflow = true
 		return
 	}
+	incoming.element, incoming.priority = event.Element, event.Priority
+	incoming.seq, incoming.enqueued = q.nextSequence(), time.Now()
+	//Count
+	defer func() {
+		if overflow {
+			q.counters.overflows++
+			q.runHooks(OnOverflow, incoming.element, incoming.priority)
+			q.publish(Overflowed, incoming)
+		} else {
+			q.counters.enqueued++
+			q.runHooks(AfterEnqueue, incoming.element, incoming.priority)
+			q.publish(Enqueued, incoming)
+			q.watermark()
+			q.wakeup()
+		}
+	}()
+	//Check if queue is full (overflow)
+	if q.checkIfFull() {
+		//Without spill there is nowhere to go
+		if q.spill == nil {
+			overflow = true
+			return
+		}
+		//Keep the best in memory, the loser goes to disk
+		q.age()
+		tail := q.containers[q.Len()-1]
+		if !q.before(incoming, tail) {
+			overflow = q.evict(incoming) != nil
+			return
+		}
+		if overflow = q.evict(tail) != nil; overflow {
+			return
+		}
+		q.containers[q.Len()-1] = nil
+		q.containers = q.containers[:q.Len()-1]
+	}
 	//Push
 	// heap.Push(q, container{element: element, priority: priority})
-	q.containers = append(q.containers, &container{element: element, priority: priority})
-	sort.Sort(q)
+	q.containers = append(q.containers, incoming)
+	q.reorder()
 	return
 }
 
 //dequeue performs the dequeue logic
-func (q *queue) dequeue() (underflow bool, element interface{}, priority int) {
+func (q *queue) dequeue() (underflow bool, element interface{}, priority int, head *container) {
+	//Nothing is handed out while paused
+	if q.paused {
+		underflow = true
+		return
+	}
+	//Missed deadlines
+	q.expire()
 	//Check if queue is empty (underflow)
 	if q.checkIfEmpty() {
 		underflow = true
+		q.counters.underflows++
 		return
 	}
-	//Pop
+	//Rate limits hold the head back
+	q.age()
 	container := q.containers[0]
+	if q.rates != nil && !q.rates.ready(container.priority, time.Now()) {
+		underflow = true
+		return
+	}
+	//Hooks may refuse
+	if _, err := q.runHooks(BeforeDequeue, container.element, container.priority); err != nil {
+		underflow = true
+		return
+	}
+	if q.rates != nil {
+		q.rates.take(container.priority)
+	}
+	//Pop
 	element = container.element
 	priority = container.priority
 	q.containers[0] = nil //Come garbage collect
 	q.containers = q.containers[1:]
+	//Count
+	container.attempts++
+	q.miss(container, time.Now())
+	q.counters.dequeued++
+	q.counters.latency.observe(time.Since(container.enqueued))
+	//Page in from disk
+	q.fill()
+	q.publish(Dequeued, container)
+	q.watermark()
+	//Hooks may change or drop the element
+	event, err := q.runHooks(AfterDequeue, element, priority)
+	if err != nil {
+		element, priority, underflow = nil, 0, true
+		return
+	}
+	element, priority = event.Element, event.Priority
+	head = container
 	return
 }
 
@@ -454,6 +655,18 @@ func (q *queue) dequeue() (underflow bool, element interface{}, priority int) {
 // Sort
 //---------------------------------------------------------------------------------------------------
 
+//before reports if container a should be dequeued before container b in the queue's order
+//(deadline, then aging, then priority and sequence)
+func (q *queue) before(a, b *container) bool {
+	if before, decided := q.ahead(a, b); decided {
+		return before
+	}
+	if q.aging != nil {
+		return q.aging.before(a, b)
+	}
+	return a.before(b)
+}
+
 //Len implements Length
 func (q *queue) Len() int {
 	return len(q.containers)
@@ -462,7 +675,7 @@ func (q *queue) Len() int {
 //Less implements Length
 //Note: This is technically backwards to make it a "max"
 func (q *queue) Less(i, j int) bool {
-	return q.containers[i].priority > q.containers[j].priority
+	return q.before(q.containers[i], q.containers[j])
 }
 
 //Swap implements Swap
@@ -0,0 +1,239 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	//ErrClosed is returned when waiting on a closed queue
+	ErrClosed = errors.New("queue: closed")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//RateConfig is the config of rate limited dequeue
+type RateConfig struct {
+	Rate  float64    //elements a second for the whole queue (0 is no limit)
+	Burst int        //elements at once (0 is 1)
+	Bands []RateBand //limits by priority (an element takes the first band it falls in)
+}
+
+//RateBand is a range of priorities with its own limit (on top of the whole queue's)
+type RateBand struct {
+	Low   int     //lowest priority (inclusive, math.MinInt for no bound)
+	High  int     //highest priority (inclusive, math.MaxInt for no bound)
+	Rate  float64 //elements a second (0 is no limit)
+	Burst int     //elements at once (0 is 1)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//RateLimit provides methods of limiting (and waiting on) dequeue
+type RateLimit interface {
+	//SetRateLimit will set (or with a zero config clear) the dequeue rate limits
+	SetRateLimit(config RateConfig)
+	//DequeueWait will wait for and dequeue a single element
+	DequeueWait(ctx context.Context) (element interface{}, priority int, err error)
+}
+
+//---------------------------------------------------------------------------------------------------
+// RateLimit Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetRateLimit will set (or with a zero config clear) the dequeue rate limits
+//Limits apply to the head: while the head is out of tokens Dequeue reports underflow (not counted
+//as one) rather than skipping ahead, and DequeueWait waits for the token.
+func (q *queue) SetRateLimit(config RateConfig) {
+	q.Lock()
+	defer q.Unlock()
+	q.rates = newRates(config)
+	q.wakeup()
+}
+
+//DequeueWait will wait for and dequeue a single element
+//It waits while the queue is empty, paused or out of tokens, until ctx is done (ctx.Err()) or the
+//queue is closed (ErrClosed).
+func (q *queue) DequeueWait(ctx context.Context) (element interface{}, priority int, err error) {
+	for {
+		//Watch before looking so nothing enqueued in between is missed
+		wake, delay, closed := q.watch()
+		if closed {
+			err = ErrClosed
+			return
+		}
+		var underflow bool
+		if element, priority, underflow = q.poll(); !underflow {
+			return
+		}
+		//Wait for a change or the next token
+		if err = wait(ctx, wake, delay); err != nil {
+			return
+		}
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//wait will wait for a wake up, a delay (if any) or ctx
+func wait(ctx context.Context, wake <-chan struct{}, delay time.Duration) (err error) {
+	var timeout <-chan time.Time
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-wake:
+	case <-timeout:
+	}
+	return
+}
+
+//rates are the dequeue limits
+type rates struct {
+	queue *limiter //nil if no limit
+	bands []rateBand
+}
+
+//rateBand is the limit of a range of priorities
+type rateBand struct {
+	low, high int
+	limiter   *limiter
+}
+
+//newRates returns the limits of a config (nil if none)
+func newRates(config RateConfig) (r *rates) {
+	if config.Rate <= 0 && len(config.Bands) == 0 {
+		return
+	}
+	r = &rates{}
+	if config.Rate > 0 {
+		r.queue = newLimiter(config.Rate, burst(config.Burst))
+	}
+	for _, band := range config.Bands {
+		if band.Rate <= 0 {
+			continue
+		}
+		r.bands = append(r.bands, rateBand{low: band.Low, high: band.High, limiter: newLimiter(band.Rate, burst(band.Burst))})
+	}
+	return
+}
+
+//burst returns a burst (at least 1)
+func burst(b int) int {
+	if b < 1 {
+		return 1
+	}
+	return b
+}
+
+//limiters returns the limiters that apply to a priority
+func (r *rates) limiters(priority int) (limiters []*limiter) {
+	if r.queue != nil {
+		limiters = append(limiters, r.queue)
+	}
+	for _, band := range r.bands {
+		if priority >= band.low && priority <= band.high {
+			limiters = append(limiters, band.limiter)
+			break
+		}
+	}
+	return
+}
+
+//ready reports if every limit of a priority has a token
+func (r *rates) ready(priority int, now time.Time) (ok bool) {
+	for _, l := range r.limiters(priority) {
+		if !l.ready(now) {
+			return
+		}
+	}
+	ok = true
+	return
+}
+
+//take will take a token from every limit of a priority
+func (r *rates) take(priority int) {
+	for _, l := range r.limiters(priority) {
+		l.tokens--
+	}
+}
+
+//delay returns how long until every limit of a priority has a token
+func (r *rates) delay(priority int, now time.Time) (delay time.Duration) {
+	for _, l := range r.limiters(priority) {
+		if d := l.delay(now); d > delay {
+			delay = d
+		}
+	}
+	return
+}
+
+//wakeup will wake everything waiting on the queue (locked)
+func (q *queue) wakeup() {
+	if q.wake != nil {
+		close(q.wake)
+		q.wake = nil
+	}
+}
+
+//limiter is a token bucket
+type limiter struct {
+	rate   float64 //tokens a second
+	burst  float64 //most tokens held
+	tokens float64
+	last   time.Time
+}
+
+//newLimiter returns a full token bucket
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+//refill will add the tokens earned since last time
+func (l *limiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+//ready reports if there is a token
+func (l *limiter) ready(now time.Time) (ok bool) {
+	l.refill(now)
+	ok = l.tokens >= 1
+	return
+}
+
+//allow will take a token if there is one
+func (l *limiter) allow(now time.Time) (ok bool) {
+	if ok = l.ready(now); ok {
+		l.tokens--
+	}
+	return
+}
+
+//delay returns how long until there is a token
+func (l *limiter) delay(now time.Time) (delay time.Duration) {
+	if l.ready(now) {
+		return
+	}
+	delay = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+	return
+}
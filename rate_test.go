@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// RateLimit
+//---------------------------------------------------------------------------------------------------
+
+//TestSetRateLimit will test non-blocking dequeue against the limits
+func TestSetRateLimit(t *testing.T) {
+	const name string = "SetRateLimit"
+	cases := map[string]struct {
+		iConfig     RateConfig
+		iPriorities []int
+		oElements   []interface{}
+	}{
+		"No_Limit": {
+			iPriorities: []int{0, 0, 0},
+			oElements:   []interface{}{0, 1, 2},
+		},
+		"Queue_Burst": {
+			iConfig:     RateConfig{Rate: 0.001, Burst: 2},
+			iPriorities: []int{0, 0, 0},
+			oElements:   []interface{}{0, 1},
+		},
+		"Band": {
+			iConfig: RateConfig{Bands: []RateBand{
+				{Low: 10, High: math.MaxInt, Rate: 0.001, Burst: 1},
+			}},
+			iPriorities: []int{10, 10, 0},
+			oElements:   []interface{}{0},
+		},
+		"Band_Other": {
+			iConfig: RateConfig{Bands: []RateBand{
+				{Low: 10, High: math.MaxInt, Rate: 0.001, Burst: 1},
+			}},
+			iPriorities: []int{10, 0, 0},
+			oElements:   []interface{}{0, 1, 2},
+		},
+		"Queue_And_Band": {
+			iConfig: RateConfig{Rate: 0.001, Burst: 2, Bands: []RateBand{
+				{Low: math.MinInt, High: 0, Rate: 1000, Burst: 5},
+			}},
+			iPriorities: []int{0, 0, 0},
+			oElements:   []interface{}{0, 1},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(len(c.iPriorities), true)
+		testQueue.SetRateLimit(c.iConfig)
+		for index, priority := range c.iPriorities {
+			testQueue.EnqueuePriority(index, priority)
+		}
+		//Dequeue until held back
+		var elements []interface{}
+		for range c.iPriorities {
+			element, underflow := testQueue.Dequeue()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, uint64(0), testQueue.Stats().Underflows, fmt.Sprintf("%s :Underflows", msg))
+		testQueue.Close()
+	}
+}
+
+//TestDequeueWait will test waiting for elements and tokens
+func TestDequeueWait(t *testing.T) {
+	const name string = "DequeueWait"
+	cases := map[string]struct {
+		iConfig   RateConfig
+		iEnqueue  int           //enqueued up front
+		iLater    bool          //enqueue one more after a while
+		iPaused   bool          //paused then resumed after a while
+		iClose    bool          //close after a while
+		iTimeout  time.Duration //for the whole case
+		oElements int
+		oMinTime  time.Duration
+		oErr      error
+	}{
+		"Ready": {
+			iEnqueue:  2,
+			iTimeout:  time.Second,
+			oElements: 2,
+		},
+		"Rate": {
+			iConfig:   RateConfig{Rate: 50, Burst: 1},
+			iEnqueue:  3,
+			iTimeout:  time.Second,
+			oElements: 3,
+			oMinTime:  35 * time.Millisecond,
+		},
+		"Later": {
+			iLater:    true,
+			iTimeout:  time.Second,
+			oElements: 1,
+		},
+		"Resumed": {
+			iEnqueue:  1,
+			iPaused:   true,
+			iTimeout:  time.Second,
+			oElements: 1,
+		},
+		"Timeout": {
+			iTimeout: 20 * time.Millisecond,
+			oErr:     context.DeadlineExceeded,
+		},
+		"Closed": {
+			iClose:   true,
+			iTimeout: time.Second,
+			oErr:     ErrClosed,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(5, true)
+		testQueue.SetRateLimit(c.iConfig)
+		for i := 0; i < c.iEnqueue; i++ {
+			testQueue.Enqueue(i)
+		}
+		if c.iPaused {
+			testQueue.Pause()
+		}
+		go func(later, paused, closing bool) {
+			time.Sleep(10 * time.Millisecond)
+			switch {
+			case later:
+				testQueue.Enqueue(0)
+			case paused:
+				testQueue.Resume()
+			case closing:
+				testQueue.Close()
+			}
+		}(c.iLater, c.iPaused, c.iClose)
+		//Wait
+		ctx, cancel := context.WithTimeout(context.Background(), c.iTimeout)
+		start := time.Now()
+		elements := 0
+		var err error
+		for elements < c.oElements || c.oErr != nil {
+			if _, _, err = testQueue.DequeueWait(ctx); err != nil {
+				break
+			}
+			elements++
+		}
+		cancel()
+		//Assert
+		assert.Equal(t, c.oErr, err, fmt.Sprintf("%s :Error", msg))
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.True(t, time.Since(start) >= c.oMinTime, fmt.Sprintf("%s :Time", msg))
+		assert.Equal(t, uint64(0), testQueue.Stats().Underflows, fmt.Sprintf("%s :Underflows", msg))
+		testQueue.Close()
+	}
+}
+
+//TestLimiterDelay will test the time until a token
+func TestLimiterDelay(t *testing.T) {
+	const name string = "LimiterDelay"
+	start := time.Unix(0, 0)
+	l := newLimiter(10, 1)
+	assert.Equal(t, time.Duration(0), l.delay(start), fmt.Sprintf("%s :Full", name))
+	l.allow(start)
+	assert.Equal(t, 100*time.Millisecond, l.delay(start), fmt.Sprintf("%s :Empty", name))
+	assert.Equal(t, 60*time.Millisecond, l.delay(start.Add(40*time.Millisecond)), fmt.Sprintf("%s :Partial", name))
+}
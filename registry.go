@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	//ErrRegistered is returned when a name is already registered
+	ErrRegistered = errors.New("queue: name already registered")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Registry
+//---------------------------------------------------------------------------------------------------
+
+//Registry holds named queues so they can be exported or inspected together
+type Registry struct {
+	sync.RWMutex
+	queues map[string]Statistics
+}
+
+//NewRegistry returns a new registry
+func NewRegistry() *Registry {
+	return &Registry{queues: make(map[string]Statistics)}
+}
+
+//Register will add a queue under a name
+func (r *Registry) Register(name string, q Statistics) (err error) {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.queues[name]; ok {
+		err = ErrRegistered
+		return
+	}
+	r.queues[name] = q
+	return
+}
+
+//Unregister will remove a queue
+func (r *Registry) Unregister(name string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.queues, name)
+}
+
+//Get will return a queue by name
+func (r *Registry) Get(name string) (q Statistics, ok bool) {
+	r.RLock()
+	defer r.RUnlock()
+	q, ok = r.queues[name]
+	return
+}
+
+//Names will return the registered names (sorted)
+func (r *Registry) Names() (names []string) {
+	r.RLock()
+	defer r.RUnlock()
+	for name := range r.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
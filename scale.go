@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultScaleInterval is the default time between scaling decisions
+	DefaultScaleInterval time.Duration = time.Second
+	//DefaultScaleCooldown is the default time after scaling before scaling the same way again
+	DefaultScaleCooldown time.Duration = 10 * time.Second
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//ScaleConfig is the config of pool autoscaling
+//The pool grows when any threshold set is passed (length per worker, oldest age, handler latency)
+//and shrinks by one when the queue is quiet: every threshold set is under half of itself.
+type ScaleConfig struct {
+	Min             int           //fewest workers (at least 1)
+	Max             int           //most workers (at least Min)
+	Interval        time.Duration //time between decisions (0 is DefaultScaleInterval)
+	UpCooldown      time.Duration //time after scaling before growing (0 is DefaultScaleCooldown)
+	DownCooldown    time.Duration //time after scaling before shrinking (0 is DefaultScaleCooldown)
+	LengthPerWorker int           //grow when the length passes this many per worker (0 ignores length)
+	MaxAge          time.Duration //grow when the oldest element waited longer (0 ignores age)
+	MaxLatency      time.Duration //grow when the average handler time is longer (0 ignores latency)
+	//OnScale is called with every decision and returns the workers wanted (Target to accept it)
+	//What it returns is kept within Min and Max but not held back by the cooldowns.
+	OnScale func(decision ScaleDecision) (workers int)
+}
+
+//ScaleDecision is a single scaling decision
+type ScaleDecision struct {
+	Workers   int           //workers running
+	Target    int           //workers proposed
+	Reason    string        //why (length, age, latency, quiet, cooldown or hold)
+	Length    int           //length of the queue
+	OldestAge time.Duration //how long the oldest element has waited
+	Latency   time.Duration //average handler time since the last decision
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//busy is the handler time since the last decision
+type busy struct {
+	sync.Mutex
+	total time.Duration
+	count int
+}
+
+//add will add the time of a single handler
+func (b *busy) add(d time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+	b.total += d
+	b.count++
+}
+
+//take returns the average handler time and starts over
+func (b *busy) take() (average time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+	if b.count > 0 {
+		average = b.total / time.Duration(b.count)
+	}
+	b.total, b.count = 0, 0
+	return
+}
+
+//defaults returns the config with its defaults filled in
+func (s ScaleConfig) defaults() ScaleConfig {
+	if s.Min < 1 {
+		s.Min = 1
+	}
+	if s.Max < s.Min {
+		s.Max = s.Min
+	}
+	if s.Interval <= 0 {
+		s.Interval = DefaultScaleInterval
+	}
+	if s.UpCooldown <= 0 {
+		s.UpCooldown = DefaultScaleCooldown
+	}
+	if s.DownCooldown <= 0 {
+		s.DownCooldown = DefaultScaleCooldown
+	}
+	return s
+}
+
+//clamp keeps a number of workers within Min and Max
+func (s *ScaleConfig) clamp(workers int) int {
+	if workers < s.Min {
+		return s.Min
+	}
+	if workers > s.Max {
+		return s.Max
+	}
+	return workers
+}
+
+//decide proposes a number of workers
+func (s *ScaleConfig) decide(d ScaleDecision, sinceScaled time.Duration) ScaleDecision {
+	d.Target, d.Reason = d.Workers, "hold"
+	//Grow
+	up := ""
+	switch {
+	case s.LengthPerWorker > 0 && d.Length > d.Workers*s.LengthPerWorker:
+		up = "length"
+	case s.MaxAge > 0 && d.OldestAge > s.MaxAge:
+		up = "age"
+	case s.MaxLatency > 0 && d.Latency > s.MaxLatency:
+		up = "latency"
+	}
+	if up != "" {
+		target := d.Workers + 1
+		if s.LengthPerWorker > 0 {
+			if wanted := (d.Length + s.LengthPerWorker - 1) / s.LengthPerWorker; wanted > target {
+				target = wanted
+			}
+		}
+		if target = s.clamp(target); target == d.Workers {
+			return d
+		}
+		if sinceScaled < s.UpCooldown {
+			d.Reason = "cooldown"
+			return d
+		}
+		d.Target, d.Reason = target, up
+		return d
+	}
+	//Shrink when quiet
+	quiet := (s.LengthPerWorker <= 0 || d.Length*2 <= (d.Workers-1)*s.LengthPerWorker) &&
+		(s.MaxAge <= 0 || d.OldestAge*2 <= s.MaxAge) &&
+		(s.MaxLatency <= 0 || d.Latency*2 <= s.MaxLatency)
+	if !quiet || d.Workers <= s.Min {
+		return d
+	}
+	if sinceScaled < s.DownCooldown {
+		d.Reason = "cooldown"
+		return d
+	}
+	d.Target, d.Reason = d.Workers-1, "quiet"
+	return d
+}
+
+//autoscale will make a decision every interval until the pool stops
+func (p *Pool) autoscale(ctx context.Context) {
+	scale := p.config.Scale
+	ticker := time.NewTicker(scale.Interval)
+	defer ticker.Stop()
+	scaled := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		//Look at the queue
+		decision := ScaleDecision{Workers: p.taking(), Latency: p.busy.take()}
+		if info, ok := p.queue.(Info); ok {
+			decision.Length = info.GetLength()
+		}
+		if stats, ok := p.queue.(Statistics); ok && scale.MaxAge > 0 {
+			decision.OldestAge = stats.Stats().OldestAge
+		}
+		//Decide
+		decision = scale.decide(decision, time.Since(scaled))
+		target := decision.Target
+		if scale.OnScale != nil {
+			target = scale.clamp(scale.OnScale(decision))
+		}
+		if target == decision.Workers {
+			continue
+		}
+		p.mutex.Lock()
+		select {
+		case <-p.stop:
+		default:
+			p.resize(ctx, target)
+		}
+		p.mutex.Unlock()
+		scaled = time.Now()
+	}
+}
@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Scale
+//---------------------------------------------------------------------------------------------------
+
+//TestScaleDecide will test the scaling decisions
+func TestScaleDecide(t *testing.T) {
+	const name string = "ScaleDecide"
+	config := ScaleConfig{
+		Min:             1,
+		Max:             5,
+		UpCooldown:      time.Second,
+		DownCooldown:    time.Minute,
+		LengthPerWorker: 10,
+		MaxAge:          time.Second,
+		MaxLatency:      100 * time.Millisecond,
+	}
+	cases := map[string]struct {
+		iDecision ScaleDecision
+		iSince    time.Duration
+		oTarget   int
+		oReason   string
+	}{
+		"Hold": {
+			iDecision: ScaleDecision{Workers: 2, Length: 15},
+			iSince:    time.Hour,
+			oTarget:   2,
+			oReason:   "hold",
+		},
+		"Length": {
+			iDecision: ScaleDecision{Workers: 2, Length: 35},
+			iSince:    time.Hour,
+			oTarget:   4,
+			oReason:   "length",
+		},
+		"Length_Capped": {
+			iDecision: ScaleDecision{Workers: 2, Length: 500},
+			iSince:    time.Hour,
+			oTarget:   5,
+			oReason:   "length",
+		},
+		"At_Max": {
+			iDecision: ScaleDecision{Workers: 5, Length: 500},
+			iSince:    time.Hour,
+			oTarget:   5,
+			oReason:   "hold",
+		},
+		"Age": {
+			iDecision: ScaleDecision{Workers: 2, OldestAge: 2 * time.Second},
+			iSince:    time.Hour,
+			oTarget:   3,
+			oReason:   "age",
+		},
+		"Latency": {
+			iDecision: ScaleDecision{Workers: 2, Latency: time.Second},
+			iSince:    time.Hour,
+			oTarget:   3,
+			oReason:   "latency",
+		},
+		"Up_Cooldown": {
+			iDecision: ScaleDecision{Workers: 2, Length: 35},
+			iSince:    time.Millisecond,
+			oTarget:   2,
+			oReason:   "cooldown",
+		},
+		"Quiet": {
+			iDecision: ScaleDecision{Workers: 3, Length: 2},
+			iSince:    time.Hour,
+			oTarget:   2,
+			oReason:   "quiet",
+		},
+		"Quiet_At_Min": {
+			iDecision: ScaleDecision{Workers: 1},
+			iSince:    time.Hour,
+			oTarget:   1,
+			oReason:   "hold",
+		},
+		"Down_Cooldown": {
+			iDecision: ScaleDecision{Workers: 3},
+			iSince:    time.Second,
+			oTarget:   3,
+			oReason:   "cooldown",
+		},
+		"Not_Quiet": {
+			iDecision: ScaleDecision{Workers: 3, OldestAge: 600 * time.Millisecond},
+			iSince:    time.Hour,
+			oTarget:   3,
+			oReason:   "hold",
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Decide
+		decision := config.decide(c.iDecision, c.iSince)
+		//Assert
+		assert.Equal(t, c.oTarget, decision.Target, fmt.Sprintf("%s :Target", msg))
+		assert.Equal(t, c.oReason, decision.Reason, fmt.Sprintf("%s :Reason", msg))
+	}
+}
+
+//TestPoolAutoscale will test a pool growing under load and shrinking once drained
+func TestPoolAutoscale(t *testing.T) {
+	const name string = "PoolAutoscale"
+	cases := map[string]struct {
+		iOverride int //what OnScale returns (0 accepts)
+		oPeak     int
+		oEnd      int
+	}{
+		"Scale": {
+			oPeak: 4,
+			oEnd:  1,
+		},
+		"Override": {
+			iOverride: 2,
+			oPeak:     2,
+			oEnd:      2,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(10, false)
+		for i := 0; i < 8; i++ {
+			testQueue.Enqueue(i)
+		}
+		//Pool with handlers held until released
+		release := make(chan struct{})
+		pool := NewPool(testQueue, func(ctx context.Context, element interface{}, priority int, meta Meta) error {
+			<-release
+			return nil
+		}, PoolConfig{
+			Poll: time.Millisecond,
+			Scale: &ScaleConfig{
+				Min:             1,
+				Max:             4,
+				Interval:        5 * time.Millisecond,
+				UpCooldown:      time.Nanosecond,
+				DownCooldown:    time.Nanosecond,
+				LengthPerWorker: 1,
+				OnScale: func(decision ScaleDecision) int {
+					if c.iOverride > 0 {
+						return c.iOverride
+					}
+					return decision.Target
+				},
+			},
+		})
+		pool.Start(context.Background())
+		//Grow
+		waitWorkers(t, msg, pool, c.oPeak)
+		//Drain and shrink
+		close(release)
+		for testQueue.GetLength() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		waitWorkers(t, msg, pool, c.oEnd)
+		assert.NoError(t, pool.Shutdown(context.Background()), fmt.Sprintf("%s :Shutdown", msg))
+		assert.Equal(t, 0, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+		assert.Equal(t, 0, pool.GetWorkers(), fmt.Sprintf("%s :Stopped", msg))
+		testQueue.Close()
+	}
+}
+
+//waitWorkers waits for a pool to have a number of workers
+func waitWorkers(t *testing.T, msg string, pool *Pool, workers int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetWorkers() != workers {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s :workers %d, wanted %d", msg, pool.GetWorkers(), workers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
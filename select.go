@@ -0,0 +1,212 @@
+package queue
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//SelectConfig is the config of a selector
+type SelectConfig struct {
+	Weights []int         //weight of each queue, taken in turn (nil is strict preference in order, missing is 1)
+	Poll    time.Duration //wait between looks at queues not made by NewQueue (0 is DefaultPollInterval)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Selector
+//---------------------------------------------------------------------------------------------------
+
+//Selector dequeues from whichever of several queues is ready
+type Selector struct {
+	mutex  sync.Mutex
+	queues []DequeuePriority
+	config SelectConfig
+	next   int  //queue whose turn it is (with weights)
+	credit int  //what is left of its turn
+	turn   bool //the queue whose turn it is has had its credit
+}
+
+//NewSelector returns a new selector over queues
+func NewSelector(config SelectConfig, queues ...DequeuePriority) (s *Selector) {
+	if config.Poll <= 0 {
+		config.Poll = DefaultPollInterval
+	}
+	s = &Selector{queues: queues, config: config}
+	return
+}
+
+//Select will wait for and dequeue a single element from the first queue ready
+//With no weights the queues are preferred in order; with weights each takes up to its weight in
+//turn while it has elements. Waiting on an empty queue is not an underflow. It waits until ctx is
+//done (ctx.Err()) or every queue is closed (ErrClosed).
+func (s *Selector) Select(ctx context.Context) (index int, element interface{}, priority int, err error) {
+	for {
+		//Watch before looking so nothing enqueued in between is missed
+		var wakes []<-chan struct{}
+		var delay time.Duration
+		open, foreign := 0, false
+		for _, q := range s.queues {
+			sq, ok := q.(selectable)
+			if !ok {
+				foreign = true
+				continue
+			}
+			wake, d, closed := sq.watch()
+			if closed {
+				continue
+			}
+			open++
+			wakes = append(wakes, wake)
+			if d > 0 && (delay == 0 || d < delay) {
+				delay = d
+			}
+		}
+		if open == 0 && !foreign {
+			err = ErrClosed
+			return
+		}
+		var ok bool
+		if index, element, priority, ok = s.take(); ok {
+			return
+		}
+		if foreign && (delay == 0 || s.config.Poll < delay) {
+			delay = s.config.Poll
+		}
+		if err = waitAny(ctx, wakes, delay); err != nil {
+			return
+		}
+	}
+}
+
+//Select will wait for and dequeue a single element from the first of queues ready (in order of
+//preference)
+func Select(ctx context.Context, queues ...DequeuePriority) (index int, element interface{}, priority int, err error) {
+	index, element, priority, err = NewSelector(SelectConfig{}, queues...).Select(ctx)
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//selectable is a queue that can be waited on without polling
+type selectable interface {
+	//watch returns what to wait on for a change, how long until the head is ready (0 if not
+	//held back) and if it is closed
+	watch() (wake <-chan struct{}, delay time.Duration, closed bool)
+	//poll will dequeue a single element if one is ready, without counting an underflow
+	poll() (element interface{}, priority int, underflow bool)
+}
+
+//try will dequeue from a single queue
+func try(q DequeuePriority) (element interface{}, priority int, underflow bool) {
+	if sq, ok := q.(selectable); ok {
+		element, priority, underflow = sq.poll()
+		return
+	}
+	element, priority, underflow = q.DequeuePriority()
+	return
+}
+
+//take will dequeue from the first queue ready
+func (s *Selector) take() (index int, element interface{}, priority int, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var underflow bool
+	//Preference
+	if len(s.config.Weights) == 0 {
+		for index = range s.queues {
+			if element, priority, underflow = try(s.queues[index]); !underflow {
+				ok = true
+				return
+			}
+		}
+		return
+	}
+	//Weighted turns (an empty queue ends its turn)
+	for tries := 0; tries < len(s.queues); tries++ {
+		index = s.next
+		if !s.turn {
+			s.turn = true
+			s.credit = s.weight(index)
+		}
+		if element, priority, underflow = try(s.queues[index]); underflow {
+			s.advance()
+			continue
+		}
+		if s.credit--; s.credit < 1 {
+			s.advance()
+		}
+		ok = true
+		return
+	}
+	return
+}
+
+//weight returns the weight of a queue (at least 1)
+func (s *Selector) weight(index int) (weight int) {
+	weight = 1
+	if index < len(s.config.Weights) && s.config.Weights[index] > 1 {
+		weight = s.config.Weights[index]
+	}
+	return
+}
+
+//advance will end the current turn (locked)
+func (s *Selector) advance() {
+	s.next = (s.next + 1) % len(s.queues)
+	s.turn = false
+}
+
+//waitAny will wait for any wake up, a delay (if any) or ctx
+func waitAny(ctx context.Context, wakes []<-chan struct{}, delay time.Duration) (err error) {
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+	for _, wake := range wakes {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(wake)})
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+	}
+	if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+		err = ctx.Err()
+	}
+	return
+}
+
+//watch returns what to wait on for a change, how long until the head is ready and if closed
+func (q *queue) watch() (wake <-chan struct{}, delay time.Duration, closed bool) {
+	q.Lock()
+	defer q.Unlock()
+	if closed = q.size == 0; closed {
+		return
+	}
+	if q.wake == nil {
+		q.wake = make(chan struct{})
+	}
+	wake = q.wake
+	if !q.paused && !q.checkIfEmpty() && q.rates != nil {
+		q.age()
+		delay = q.rates.delay(q.containers[0].priority, time.Now())
+	}
+	return
+}
+
+//poll will dequeue a single element if one is ready, without counting an underflow
+func (q *queue) poll() (element interface{}, priority int, underflow bool) {
+	q.Lock()
+	defer q.Unlock()
+	//Waiting on an empty queue is not an underflow
+	if q.paused || q.checkIfEmpty() {
+		underflow = true
+		return
+	}
+	underflow, element, priority, _ = q.dequeue()
+	return
+}
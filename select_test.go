@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Select
+//---------------------------------------------------------------------------------------------------
+
+//TestSelect will test dequeuing from whichever queue is ready
+func TestSelect(t *testing.T) {
+	const name string = "Select"
+	cases := map[string]struct {
+		iWeights []int
+		iCounts  []int //enqueued up front in each queue
+		iLater   int   //queue to enqueue into after a while (-1 for none)
+		iClose   bool  //close every queue up front
+		oIndexes []int
+		oErr     error
+	}{
+		"Preference": {
+			iCounts:  []int{1, 2, 0},
+			iLater:   -1,
+			oIndexes: []int{0, 1, 1},
+		},
+		"Weights": {
+			iWeights: []int{2, 1, 1},
+			iCounts:  []int{4, 2, 1},
+			iLater:   -1,
+			oIndexes: []int{0, 0, 1, 2, 0, 0, 1},
+		},
+		"Later": {
+			iCounts:  []int{0, 0, 0},
+			iLater:   2,
+			oIndexes: []int{2},
+		},
+		"Timeout": {
+			iCounts: []int{0, 0, 0},
+			iLater:  -1,
+			oErr:    context.DeadlineExceeded,
+		},
+		"Closed": {
+			iCounts: []int{0, 0, 0},
+			iLater:  -1,
+			iClose:  true,
+			oErr:    ErrClosed,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queues
+		var queues []DequeuePriority
+		var testQueues []interface {
+			Owner
+			EnqueuePriority
+			Statistics
+		}
+		for _, count := range c.iCounts {
+			testQueue := NewQueue(5, true)
+			for i := 0; i < count; i++ {
+				testQueue.EnqueuePriority(i, 0)
+			}
+			if c.iClose {
+				testQueue.Close()
+			}
+			queues = append(queues, testQueue)
+			testQueues = append(testQueues, testQueue)
+		}
+		if c.iLater >= 0 {
+			go func(later EnqueuePriority) {
+				time.Sleep(10 * time.Millisecond)
+				later.EnqueuePriority(0, 0)
+			}(testQueues[c.iLater])
+		}
+		//Select until empty
+		selector := NewSelector(SelectConfig{Weights: c.iWeights}, queues...)
+		var indexes []int
+		var err error
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			var index int
+			index, _, _, err = selector.Select(ctx)
+			cancel()
+			if err != nil {
+				break
+			}
+			indexes = append(indexes, index)
+		}
+		//Assert
+		assert.Equal(t, c.oIndexes, indexes, fmt.Sprintf("%s :Indexes", msg))
+		if c.oErr != nil {
+			assert.Equal(t, c.oErr, err, fmt.Sprintf("%s :Error", msg))
+		}
+		for _, testQueue := range testQueues {
+			assert.Equal(t, uint64(0), testQueue.Stats().Underflows, fmt.Sprintf("%s :Underflows", msg))
+			testQueue.Close()
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package queue
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+const (
+	//DefaultSlotSize is the max encoded element size (bytes) of a shared queue slot
+	DefaultSlotSize int = 1024
+	//sharedMagic identifies a shared queue file
+	sharedMagic string = "GQSH"
+	//sharedHeaderSize is the size of the file header (magic, capacity, slot size, state, sequence, length, flags, version)
+	sharedHeaderSize int = 64
+	//sharedChecked flags a file whose slots carry a checksum
+	sharedChecked uint32 = 1 << 0
+	//slotHeaderSize is the size of the header in front of every slot (used, length, priority, seq)
+	slotHeaderSize int = 4 + 4 + 8 + 8
+	//slotChecksumSize is the size of the checksum following the header of a checked slot
+	slotChecksumSize int = 4
+	//sharedClean marks the file as consistent
+	sharedClean uint32 = 0
+	//sharedDirty marks the file as being changed (a crash leaves it dirty)
+	sharedDirty uint32 = 1
+	//slotFree marks a slot as free
+	slotFree byte = 0
+	//slotUsed marks a slot as holding an element
+	slotUsed byte = 1
+)
+
+var (
+	//ErrSharedUnsupported is returned when the platform can not map files
+	ErrSharedUnsupported = errors.New("queue: shared queue not supported on this platform")
+	//ErrSharedInvalid is returned when a file is not a shared queue
+	ErrSharedInvalid = errors.New("queue: not a shared queue file")
+	//ErrSharedMismatch is returned when a file was created with a different config
+	ErrSharedMismatch = errors.New("queue: shared queue config mismatch")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Config
+//---------------------------------------------------------------------------------------------------
+
+//SharedConfig configures a shared queue
+type SharedConfig struct {
+	Size     int   //number of slots (taken from the file if <= 0, else DefaultSize for a new file)
+	SlotSize int   //max encoded element size (taken from the file if <= 0, else DefaultSlotSize for a new file)
+	Codec    Codec //codec for the elements (GobCodec if nil)
+	//Layers transform what the codec encodes before it is written (e.g. Compression then Encryption)
+	Layers []Layer
+	//Recovery decides what happens to damaged slots found when opening (RecoverTruncate acts as RecoverSkip)
+	Recovery RecoveryMode
+	//OnRecover is called with what was found if anything was recovered (optional)
+	OnRecover func(report Report)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Ensure the implementation
+var _ Flush = &sharedQueue{}
+var _ Info = &sharedQueue{}
+var _ Dequeue = &sharedQueue{}
+var _ DequeuePriority = &sharedQueue{}
+var _ Enqueue = &sharedQueue{}
+var _ EnqueuePriority = &sharedQueue{}
+
+//NewSharedQueue opens (or creates) a fixed size queue stored in a memory mapped file
+//Any number of processes can open the same file. Access is serialized with a file lock that the
+//OS releases if the holder dies; the next holder repairs whatever it left half done.
+//There is no signal across processes, consumers must poll.
+//Note: An element that does not encode within SlotSize is treated as an overflow
+func NewSharedQueue(path string, config SharedConfig) (q interface {
+	Flush
+	Info
+	Dequeue
+	DequeuePriority
+	Enqueue
+	EnqueuePriority
+	//Close will unmap and close the file (the file stays)
+	Close()
+}, err error) {
+	//Check config
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	//Open
+	var file *os.File
+	if file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		return
+	}
+	s := &sharedQueue{file: file, codec: layered(config.Codec, config.Layers)}
+	if err = s.open(config); err != nil {
+		file.Close()
+		return
+	}
+	q = s
+	return
+}
+
+//sharedQueue provides a memory mapped implementation of a queue
+type sharedQueue struct {
+	sync.Mutex          //serializes goroutines (the file lock is per process)
+	file       *os.File //the backing file
+	data       []byte   //the mapping
+	codec      Codec    //codec for the elements
+	size       int      //number of slots
+	slotSize   int      //max encoded element size
+	slotHeader int      //size of the slot header (with checksum if checked)
+}
+
+//open will initialize (if new) and map the file
+func (s *sharedQueue) open(config SharedConfig) (err error) {
+	if err = lockFile(s.file); err != nil {
+		return
+	}
+	defer unlockFile(s.file)
+	//Check if new
+	var info os.FileInfo
+	if info, err = s.file.Stat(); err != nil {
+		return
+	}
+	if info.Size() == 0 {
+		size, slotSize := config.Size, config.SlotSize
+		if size <= 0 {
+			size = DefaultSize
+		}
+		if slotSize <= 0 {
+			slotSize = DefaultSlotSize
+		}
+		if err = s.file.Truncate(int64(sharedHeaderSize + size*(slotHeaderSize+slotChecksumSize+slotSize))); err != nil {
+			return
+		}
+		header := make([]byte, sharedHeaderSize)
+		copy(header, sharedMagic)
+		binary.BigEndian.PutUint32(header[4:], uint32(size))
+		binary.BigEndian.PutUint32(header[8:], uint32(slotSize))
+		binary.BigEndian.PutUint32(header[28:], sharedChecked)
+		binary.BigEndian.PutUint32(header[32:], uint32(FormatVersion))
+		if _, err = s.file.WriteAt(header, 0); err != nil {
+			return
+		}
+		info, err = s.file.Stat()
+		if err != nil {
+			return
+		}
+	}
+	//Check the header
+	header := make([]byte, sharedHeaderSize)
+	if _, err = s.file.ReadAt(header, 0); err != nil || string(header[:4]) != sharedMagic {
+		err = ErrSharedInvalid
+		return
+	}
+	if sharedVersion(header) > FormatVersion {
+		err = ErrFormatVersion
+		return
+	}
+	s.size, s.slotSize, s.slotHeader = decodeSharedHeader(header)
+	if (config.Size > 0 && s.size != config.Size) || (config.SlotSize > 0 && s.slotSize != config.SlotSize) {
+		err = ErrSharedMismatch
+		return
+	}
+	if info.Size() != int64(sharedHeaderSize+s.size*(s.slotHeader+s.slotSize)) {
+		err = ErrSharedInvalid
+		return
+	}
+	//Map
+	if s.data, err = mapFile(s.file, int(info.Size())); err != nil {
+		return
+	}
+	//Check the slots
+	var report Report
+	if scanShared(s.data, s.file.Name(), config.Recovery, true, &report) == 0 {
+		return
+	}
+	if config.Recovery == RecoverFail {
+		unmapFile(s.data)
+		s.data = nil
+		err = &CorruptionError{Report: report}
+		return
+	}
+	s.repair()
+	if config.OnRecover != nil {
+		config.OnRecover(report)
+	}
+	return
+}
+
+//decodeSharedHeader decodes the layout of a shared queue file
+func decodeSharedHeader(header []byte) (size, slotSize, slotHeader int) {
+	size = int(binary.BigEndian.Uint32(header[4:]))
+	slotSize = int(binary.BigEndian.Uint32(header[8:]))
+	slotHeader = slotHeaderSize
+	if binary.BigEndian.Uint32(header[28:])&sharedChecked != 0 {
+		slotHeader += slotChecksumSize
+	}
+	return
+}
+
+//Close will unmap and close the file (the file stays)
+func (s *sharedQueue) Close() {
+	s.Lock()
+	defer s.Unlock()
+	if s.data != nil {
+		unmapFile(s.data)
+		s.data = nil
+	}
+	s.file.Close()
+}
+
+//---------------------------------------------------------------------------------------------------
+// Flush Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Flush will flush the queue of all elements and return what was in it
+func (s *sharedQueue) Flush() (elements []interface{}, priorities []int) {
+	if !s.acquire() {
+		return
+	}
+	defer s.release()
+	for {
+		element, priority, underflow := s.dequeue()
+		if underflow {
+			return
+		}
+		elements = append(elements, element)
+		priorities = append(priorities, priority)
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Info Implementation
+//---------------------------------------------------------------------------------------------------
+
+//GetSize will return the size (max size of the queue)
+func (s *sharedQueue) GetSize() (size int) {
+	s.Lock()
+	defer s.Unlock()
+	size = s.size
+	return
+}
+
+//GetLength will return the current length of the queue
+func (s *sharedQueue) GetLength() (len int) {
+	if !s.acquire() {
+		return
+	}
+	defer s.release()
+	len = int(binary.BigEndian.Uint32(s.data[24:]))
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Dequeue Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Dequeue will dequeue a single (last) element
+func (s *sharedQueue) Dequeue() (element interface{}, underflow bool) {
+	element, _, underflow = s.DequeuePriority()
+	return
+}
+
+//DequeuePriority will dequeue a single (last) element and priority
+func (s *sharedQueue) DequeuePriority() (element interface{}, priority int, underflow bool) {
+	if !s.acquire() {
+		underflow = true
+		return
+	}
+	defer s.release()
+	element, priority, underflow = s.dequeue()
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Enqueue Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Enqueue will enqueue a single element
+func (s *sharedQueue) Enqueue(element interface{}) (overflow bool) {
+	overflow = s.EnqueuePriority(element, DefaultPriority)
+	return
+}
+
+//EnqueuePriority will enqueue a single element with priority
+func (s *sharedQueue) EnqueuePriority(element interface{}, priority int) (overflow bool) {
+	//Encode outside of the lock
+	payload, err := s.codec.Encode(element)
+	if err != nil || len(payload) > s.slotSize {
+		overflow = true
+		return
+	}
+	if !s.acquire() {
+		overflow = true
+		return
+	}
+	defer s.release()
+	//Find a free slot
+	index := -1
+	for i := 0; i < s.size; i++ {
+		if s.slot(i)[0] == slotFree {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		overflow = true
+		return
+	}
+	//Write the slot, marking it used last
+	s.markDirty()
+	slot := s.slot(index)
+	seq := binary.BigEndian.Uint64(s.data[16:])
+	binary.BigEndian.PutUint32(slot[4:], uint32(len(payload)))
+	binary.BigEndian.PutUint64(slot[8:], uint64(int64(priority)))
+	binary.BigEndian.PutUint64(slot[16:], seq)
+	copy(slot[s.slotHeader:], payload)
+	if s.slotHeader > slotHeaderSize {
+		binary.BigEndian.PutUint32(slot[slotHeaderSize:], checksum(slot[4:slotHeaderSize], payload))
+	}
+	slot[0] = slotUsed
+	binary.BigEndian.PutUint64(s.data[16:], seq+1)
+	binary.BigEndian.PutUint32(s.data[24:], binary.BigEndian.Uint32(s.data[24:])+1)
+	s.markClean()
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//acquire will lock the queue for this goroutine and process, repairing the file if needed
+func (s *sharedQueue) acquire() (ok bool) {
+	s.Lock()
+	if s.data == nil || lockFile(s.file) != nil {
+		s.Unlock()
+		return
+	}
+	//A dirty file means the last holder died mid change
+	if binary.BigEndian.Uint32(s.data[12:]) != sharedClean {
+		s.repair()
+	}
+	ok = true
+	return
+}
+
+//release will unlock the queue
+func (s *sharedQueue) release() {
+	unlockFile(s.file)
+	s.Unlock()
+}
+
+//repair will rebuild the length and sequence from the slots
+//Note: Slots are marked used last and freed first so a slot is either whole or free
+func (s *sharedQueue) repair() {
+	var length uint32
+	var sequence uint64
+	for i := 0; i < s.size; i++ {
+		slot := s.slot(i)
+		if slot[0] != slotUsed {
+			continue
+		}
+		length++
+		if seq := binary.BigEndian.Uint64(slot[16:]); seq >= sequence {
+			sequence = seq + 1
+		}
+	}
+	if seq := binary.BigEndian.Uint64(s.data[16:]); seq > sequence {
+		sequence = seq
+	}
+	binary.BigEndian.PutUint64(s.data[16:], sequence)
+	binary.BigEndian.PutUint32(s.data[24:], length)
+	s.markClean()
+}
+
+//slot returns the bytes of a slot
+func (s *sharedQueue) slot(index int) []byte {
+	offset := sharedHeaderSize + index*(s.slotHeader+s.slotSize)
+	return s.data[offset : offset+s.slotHeader+s.slotSize]
+}
+
+//markDirty marks the file as being changed
+func (s *sharedQueue) markDirty() {
+	binary.BigEndian.PutUint32(s.data[12:], sharedDirty)
+}
+
+//markClean marks the file as consistent
+func (s *sharedQueue) markClean() {
+	binary.BigEndian.PutUint32(s.data[12:], sharedClean)
+}
+
+//dequeue performs the dequeue logic (locked)
+//Note: An element that is damaged or can not be decoded is dropped
+func (s *sharedQueue) dequeue() (element interface{}, priority int, underflow bool) {
+	for {
+		//Find the best slot
+		index := -1
+		var best *container
+		for i := 0; i < s.size; i++ {
+			slot := s.slot(i)
+			if slot[0] != slotUsed {
+				continue
+			}
+			c := &container{
+				priority: int(int64(binary.BigEndian.Uint64(slot[8:]))),
+				seq:      binary.BigEndian.Uint64(slot[16:]),
+			}
+			if best == nil || c.before(best) {
+				index, best = i, c
+			}
+		}
+		if index < 0 {
+			underflow = true
+			return
+		}
+		//Read and free the slot
+		slot := s.slot(index)
+		length := int(binary.BigEndian.Uint32(slot[4:]))
+		if length > s.slotSize {
+			length = s.slotSize
+		}
+		payload := make([]byte, length)
+		copy(payload, slot[s.slotHeader:])
+		s.markDirty()
+		slot[0] = slotFree
+		binary.BigEndian.PutUint32(s.data[24:], binary.BigEndian.Uint32(s.data[24:])-1)
+		s.markClean()
+		if s.slotHeader > slotHeaderSize && checksum(slot[4:slotHeaderSize], payload) != binary.BigEndian.Uint32(slot[slotHeaderSize:]) {
+			continue
+		}
+		var err error
+		if element, err = s.codec.Decode(payload); err != nil {
+			continue
+		}
+		priority = best.priority
+		return
+	}
+}
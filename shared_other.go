@@ -0,0 +1,32 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package queue
+
+import (
+	"os"
+)
+
+//mapFile is not supported
+func mapFile(file *os.File, size int) (data []byte, err error) {
+	err = ErrSharedUnsupported
+	return
+}
+
+//unmapFile is not supported
+func unmapFile(data []byte) (err error) {
+	err = ErrSharedUnsupported
+	return
+}
+
+//lockFile is not supported
+func lockFile(file *os.File) (err error) {
+	err = ErrSharedUnsupported
+	return
+}
+
+//unlockFile is not supported
+func unlockFile(file *os.File) (err error) {
+	err = ErrSharedUnsupported
+	return
+}
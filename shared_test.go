@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Shared
+//---------------------------------------------------------------------------------------------------
+
+//TestShared will test two handles on the same file
+func TestShared(t *testing.T) {
+	const name string = "Shared"
+	cases := map[string]struct {
+		iSize       int
+		iElements   []interface{}
+		iPriorities []int
+		oOverflow   bool
+		oLength     int
+		oElements   []interface{}
+		oPriorities []int
+	}{
+		"Priority": {
+			iSize:       4,
+			iElements:   []interface{}{1, 2, 3},
+			iPriorities: []int{0, 10, 0},
+			oOverflow:   false,
+			oLength:     3,
+			oElements:   []interface{}{2, 1, 3},
+			oPriorities: []int{10, 0, 0},
+		},
+		"Overflow": {
+			iSize:       2,
+			iElements:   []interface{}{"a", "b", "c"},
+			iPriorities: []int{0, 0, 0},
+			oOverflow:   true,
+			oLength:     2,
+			oElements:   []interface{}{"a", "b"},
+			oPriorities: []int{0, 0},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+		path := filepath.Join(dir, "shared")
+		//Create a producer and a consumer
+		producer, err := NewSharedQueue(path, SharedConfig{Size: c.iSize})
+		if err == ErrSharedUnsupported {
+			t.Skip(err)
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		defer producer.Close()
+		consumer, err := NewSharedQueue(path, SharedConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer consumer.Close()
+		//Enqueue
+		var overflow bool
+		for index, element := range c.iElements {
+			overflow = producer.EnqueuePriority(element, c.iPriorities[index])
+		}
+		//Assert
+		assert.Equal(t, c.oOverflow, overflow, fmt.Sprintf("%s :Overflow", msg))
+		assert.Equal(t, c.iSize, consumer.GetSize(), fmt.Sprintf("%s :Size", msg))
+		assert.Equal(t, c.oLength, consumer.GetLength(), fmt.Sprintf("%s :Length", msg))
+		//Dequeue
+		var elements []interface{}
+		var priorities []int
+		for {
+			element, priority, underflow := consumer.DequeuePriority()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+			priorities = append(priorities, priority)
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oPriorities, priorities, fmt.Sprintf("%s :Priorities", msg))
+		assert.Equal(t, 0, producer.GetLength(), fmt.Sprintf("%s :Drained", msg))
+	}
+}
+
+//TestSharedMismatch will test opening with a different config
+func TestSharedMismatch(t *testing.T) {
+	const name string = "SharedMismatch"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "shared")
+	q, err := NewSharedQueue(path, SharedConfig{Size: 2})
+	if err == ErrSharedUnsupported {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	_, err = NewSharedQueue(path, SharedConfig{Size: 3})
+	assert.Equal(t, ErrSharedMismatch, err, name)
+}
+
+//TestSharedRepair will test recovering from a holder that died mid change
+func TestSharedRepair(t *testing.T) {
+	const name string = "SharedRepair"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	q, err := NewSharedQueue(filepath.Join(dir, "shared"), SharedConfig{Size: 3})
+	if err == ErrSharedUnsupported {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.Enqueue(1)
+	q.Enqueue(2)
+	//Leave the file dirty with a bad length (as a crash would)
+	s := q.(*sharedQueue)
+	s.markDirty()
+	binary.BigEndian.PutUint32(s.data[24:], 7)
+	//Assert
+	assert.Equal(t, 2, q.GetLength(), fmt.Sprintf("%s :Length", name))
+	elements, _ := q.Flush()
+	assert.Equal(t, []interface{}{1, 2}, elements, fmt.Sprintf("%s :Elements", name))
+}
@@ -0,0 +1,33 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package queue
+
+import (
+	"os"
+	"syscall"
+)
+
+//mapFile maps a file shared read/write
+func mapFile(file *os.File, size int) (data []byte, err error) {
+	data, err = syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	return
+}
+
+//unmapFile unmaps a file
+func unmapFile(data []byte) (err error) {
+	err = syscall.Munmap(data)
+	return
+}
+
+//lockFile takes an exclusive lock on a file (released by the OS if the process dies)
+func lockFile(file *os.File) (err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	return
+}
+
+//unlockFile releases the lock on a file
+func unlockFile(file *os.File) (err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return
+}
@@ -0,0 +1,707 @@
+package queue
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+const (
+	//DefaultSegmentSize is the size (bytes) at which a new spill segment is started
+	DefaultSegmentSize int64 = 4 << 20
+	//segmentExt is the file extension of a spill segment
+	segmentExt string = ".seg"
+	//recordHeaderSize is the size of the header in front of every record (state, priority, seq, length)
+	recordHeaderSize int = 1 + 8 + 8 + 4
+	//recordChecksumSize is the size of the checksum following the header of a checked record
+	recordChecksumSize int = 4
+	//recordConsumed marks a record as paged back in
+	recordConsumed byte = 1 << 0
+	//recordChecked marks a record as carrying a checksum
+	recordChecked byte = 1 << 1
+)
+
+var (
+	//ErrSpillEnabled is returned when spill is enabled more than once
+	ErrSpillEnabled = errors.New("queue: spill already enabled")
+	//ErrSpillNotEmpty is returned when spill is enabled on a queue holding elements
+	ErrSpillNotEmpty = errors.New("queue: spill enabled on a non-empty queue")
+	//ErrNoSpillDir is returned when no spill directory is given
+	ErrNoSpillDir = errors.New("queue: no spill directory")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Codec
+//---------------------------------------------------------------------------------------------------
+
+//Codec converts elements to and from bytes so they can leave memory
+type Codec interface {
+	//Encode will encode a single element
+	Encode(element interface{}) (data []byte, err error)
+	//Decode will decode a single element
+	Decode(data []byte) (element interface{}, err error)
+}
+
+//GobCodec is a Codec using encoding/gob
+//Note: Non basic types must be registered with gob.Register
+type GobCodec struct{}
+
+//Encode will encode a single element
+func (GobCodec) Encode(element interface{}) (data []byte, err error) {
+	var buffer bytes.Buffer
+	if err = gob.NewEncoder(&buffer).Encode(&element); err != nil {
+		return
+	}
+	data = buffer.Bytes()
+	return
+}
+
+//Decode will decode a single element
+func (GobCodec) Decode(data []byte) (element interface{}, err error) {
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&element)
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Config
+//---------------------------------------------------------------------------------------------------
+
+//SpillConfig configures spilling to disk
+type SpillConfig struct {
+	Dir         string //directory holding the segments (created if missing)
+	Codec       Codec  //codec for the elements (GobCodec if nil)
+	SegmentSize int64  //size at which a new segment is started (DefaultSegmentSize if <= 0)
+	//Layers transform what the codec encodes before it is written (e.g. Compression then Encryption)
+	Layers []Layer
+	//Recovery decides what happens to damaged segments found when enabling
+	Recovery RecoveryMode
+	//OnRecover is called with what was found if anything was recovered (optional)
+	//It is also called for each record that can not be read back later on.
+	OnRecover func(report Report)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Spill Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Spill enables spilling elements that do not fit in memory to disk
+//Elements left on disk by a previous queue using the same directory are picked back up. The queue
+//must be empty (ErrSpillNotEmpty) so handles are not reused.
+//Note: Peek and PeekPriority only see the elements held in memory
+func (q *queue) Spill(config SpillConfig) (err error) {
+	q.Lock()
+	defer q.Unlock()
+	//Check if already spilling
+	if q.spill != nil {
+		err = ErrSpillEnabled
+		return
+	}
+	//Check if empty (its handles could be on disk from a previous queue)
+	if !q.checkIfEmpty() {
+		err = ErrSpillNotEmpty
+		return
+	}
+	//Open
+	var s *spill
+	if s, err = openSpill(config); err != nil {
+		return
+	}
+	q.spill = s
+	s.index.before = q.before
+	s.reorder()
+	//Continue the sequence after what is on disk
+	if s.sequence > q.sequence {
+		q.sequence = s.sequence
+	}
+	//Page in what is on disk
+	q.fill()
+	return
+}
+
+//GetSpilled will return the number of elements currently on disk
+func (q *queue) GetSpilled() (spilled int) {
+	q.Lock()
+	defer q.Unlock()
+	if q.spill != nil {
+		spilled = q.spill.Len()
+	}
+	return
+}
+
+//fill will page in from disk until memory is full
+//Note: Everything on disk is behind everything in memory, but what is in memory may have missed
+//its deadline since it was sorted so it is sorted again
+func (q *queue) fill() {
+	filled := false
+	for q.spill != nil && q.spill.Len() > 0 && !q.checkIfFull() {
+		container, err := q.spill.pop()
+		if err != nil {
+			q.corrupt(container, err)
+			continue
+		}
+		q.containers = append(q.containers, container)
+		filled = true
+	}
+	if filled {
+		q.reorder()
+	}
+}
+
+//corrupt will count and report a record dropped as it could not be read back (locked)
+//The container holds what the index knew of it (no element).
+func (q *queue) corrupt(c *container, err error) {
+	q.counters.corrupted++
+	q.publish(Corrupted, c)
+	if q.spill.onRecover == nil {
+		return
+	}
+	report := Report{Records: 1, Dropped: 1}
+	if corruption, ok := err.(*CorruptionError); ok {
+		report.Corruptions = corruption.Report.Corruptions
+	}
+	q.spill.onRecover(report)
+}
+
+//evict will write a container to disk
+func (q *queue) evict(c *container) (err error) {
+	if err = q.spill.push(c); err == nil {
+		q.counters.evicted++
+		q.runHooks(OnEvict, c.element, c.priority)
+		q.publish(Evicted, c)
+	}
+	return
+}
+
+//rebalance will swap elements between memory and disk until memory holds the best
+func (q *queue) rebalance() {
+	q.reorder()
+	q.fill()
+	for q.spill.Len() > 0 && q.Len() > 0 {
+		//Check if the best on disk beats the worst in memory
+		tail := q.containers[q.Len()-1]
+		if !q.before(q.spill.index.list[0].stub(), tail) {
+			return
+		}
+		if err := q.evict(tail); err != nil {
+			return
+		}
+		q.containers[q.Len()-1] = nil
+		q.containers = q.containers[:q.Len()-1]
+		q.fill()
+		q.reorder()
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//spill is an on disk extension of the queue made of append only segments
+//An index of the live records is held in memory and kept as a heap in queue order.
+type spill struct {
+	dir         string
+	codec       Codec
+	segmentSize int64
+	index       records             //live records (heap)
+	segments    map[uint64]*segment //open segments by id
+	active      *segment            //segment being appended to
+	sequence    uint64              //one past the highest sequence seen on disk
+	onRecover   func(report Report) //told of records that can not be read back (nil if none)
+}
+
+//segment is a single spill file
+type segment struct {
+	id    uint64
+	file  *os.File
+	start int64 //where the records start (after the header)
+	size  int64 //bytes written
+	live  int   //records not yet consumed
+}
+
+//record locates a single element on disk
+type record struct {
+	segment  *segment
+	offset   int64
+	state    byte
+	length   uint32
+	priority int
+	seq      uint64
+	checksum uint32
+	enqueued time.Time //when the element was enqueued (when loaded if from a previous queue)
+	//Held in memory only (lost with a previous queue)
+	carried  *carried
+	attempts int
+	headers  map[string]string
+	deadline time.Time
+	missed   bool
+}
+
+//before reports if record a should be dequeued before container b
+func (a *record) before(b *container) bool {
+	return (&container{priority: a.priority, seq: a.seq}).before(b)
+}
+
+//stub returns a container with what orders record a (no element)
+func (a *record) stub() *container {
+	return &container{priority: a.priority, seq: a.seq, enqueued: a.enqueued, deadline: a.deadline, missed: a.missed}
+}
+
+//headerSize returns the size of the header in front of the payload
+func (a *record) headerSize() (size int) {
+	size = recordHeaderSize
+	if a.state&recordChecked != 0 {
+		size += recordChecksumSize
+	}
+	return
+}
+
+//openSpill opens (or creates) the spill directory and indexes what is on disk
+func openSpill(config SpillConfig) (s *spill, err error) {
+	//Check config
+	if config.Dir == "" {
+		err = ErrNoSpillDir
+		return
+	}
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = DefaultSegmentSize
+	}
+	if err = os.MkdirAll(config.Dir, 0755); err != nil {
+		return
+	}
+	s = &spill{
+		dir:         config.Dir,
+		codec:       layered(config.Codec, config.Layers),
+		segmentSize: config.SegmentSize,
+		segments:    make(map[uint64]*segment),
+		onRecover:   config.OnRecover,
+	}
+	//Load the existing segments
+	var ids []uint64
+	if ids, err = listSegments(config.Dir); err != nil {
+		return
+	}
+	var report Report
+	for _, id := range ids {
+		if err = s.load(id, config.Recovery, &report); err != nil {
+			s.close()
+			return
+		}
+	}
+	//Report
+	if len(report.Corruptions) > 0 {
+		if config.Recovery == RecoverFail {
+			s.close()
+			err = &CorruptionError{Report: report}
+			return
+		}
+		if config.OnRecover != nil {
+			config.OnRecover(report)
+		}
+	}
+	//Keep appending to the last segment and drop the empty ones before it
+	for index, id := range ids {
+		seg := s.segments[id]
+		if index == len(ids)-1 {
+			s.active = seg
+		} else if seg.live == 0 {
+			s.remove(seg)
+		}
+	}
+	heap.Init(&s.index)
+	return
+}
+
+//listSegments returns the ids of the segments in a directory (ascending)
+func listSegments(dir string) (ids []uint64, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, perr := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 16, 64)
+		if perr != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return
+}
+
+//segmentPath returns the path of a segment
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%016x%s", id, segmentExt))
+}
+
+//nextID returns the id the next new segment will get
+func (s *spill) nextID() (id uint64) {
+	if s.active != nil {
+		id = s.active.id + 1
+	}
+	return
+}
+
+//load indexes the live records of an existing segment, recovering from damage as configured
+func (s *spill) load(id uint64, mode RecoveryMode, report *Report) (err error) {
+	var file *os.File
+	if file, err = os.OpenFile(segmentPath(s.dir, id), os.O_RDWR, 0644); err != nil {
+		return
+	}
+	seg := &segment{id: id, file: file}
+	s.segments[id] = seg
+	if _, seg.start, err = readSegmentHeader(file); err != nil {
+		return
+	}
+	//Walk the records
+	var corruptions int
+	if seg.size, corruptions, err = scanSegment(file, seg.start, mode, true, report, func(rec *record) {
+		if rec.seq >= s.sequence {
+			s.sequence = rec.seq + 1
+		}
+		if rec.state&recordConsumed != 0 {
+			return
+		}
+		rec.segment, rec.enqueued = seg, time.Now()
+		seg.live++
+		s.index.list = append(s.index.list, rec)
+	}); err != nil || (corruptions > 0 && mode == RecoverFail) {
+		return
+	}
+	//Cut off anything torn or damaged so new appends start clean
+	err = file.Truncate(seg.size)
+	return
+}
+
+//scanSegment walks the records of a segment from start, calling visit for every intact one
+//On damage the bad record is reported and then skipped (if possible) or the walk stops, per mode.
+//Only with repair are skipped records marked consumed on disk. The returned size is where intact
+//records end.
+func scanSegment(file *os.File, start int64, mode RecoveryMode, repair bool, report *Report, visit func(rec *record)) (size int64, corruptions int, err error) {
+	var info os.FileInfo
+	if info, err = file.Stat(); err != nil {
+		return
+	}
+	size = start
+	report.Segments++
+	corrupt := func(offset int64, reason string) {
+		corruptions++
+		report.Corruptions = append(report.Corruptions, Corruption{Path: file.Name(), Offset: offset, Reason: reason})
+	}
+	header := make([]byte, recordHeaderSize+recordChecksumSize)
+	for size < info.Size() {
+		//Read the header
+		n, _ := file.ReadAt(header, size)
+		if n < recordHeaderSize {
+			corrupt(size, "torn record header")
+			break
+		}
+		rec := decodeHeader(header)
+		rec.offset = size
+		if rec.state&^(recordConsumed|recordChecked) != 0 {
+			corrupt(size, "invalid record state")
+			break
+		}
+		if n < rec.headerSize() {
+			corrupt(size, "torn record header")
+			break
+		}
+		//A short trailing record is an interrupted append
+		end := rec.offset + int64(rec.headerSize()) + int64(rec.length)
+		if end > info.Size() {
+			corrupt(size, "torn record")
+			break
+		}
+		report.Records++
+		//Check the payload (consumed records no longer matter)
+		if rec.state&recordChecked != 0 && rec.state&recordConsumed == 0 {
+			payload := make([]byte, rec.length)
+			if _, err = file.ReadAt(payload, rec.offset+int64(rec.headerSize())); err != nil {
+				return
+			}
+			if rec.sum(payload) != rec.checksum {
+				corrupt(size, "checksum mismatch")
+				if mode == RecoverTruncate || mode == RecoverFail {
+					break
+				}
+				report.Dropped++
+				if repair {
+					_, _ = file.WriteAt([]byte{rec.state | recordConsumed}, rec.offset)
+				}
+				size = end
+				continue
+			}
+		}
+		if rec.state&recordConsumed == 0 {
+			report.Live++
+		}
+		visit(rec)
+		size = end
+	}
+	//Anything past size is lost
+	if repair && mode != RecoverFail && size < info.Size() {
+		report.Truncated += info.Size() - size
+	}
+	return
+}
+
+//Len returns the number of live records
+func (s *spill) Len() int {
+	return s.index.Len()
+}
+
+//reorder will heap the records again after the queue order changed
+func (s *spill) reorder() {
+	heap.Init(&s.index)
+}
+
+//push writes a container to the active segment
+func (s *spill) push(c *container) (err error) {
+	var payload []byte
+	if payload, err = s.codec.Encode(c.element); err != nil {
+		return
+	}
+	//Rotate if needed
+	if s.active == nil || s.active.size >= s.segmentSize {
+		if err = s.rotate(); err != nil {
+			return
+		}
+	}
+	//Write
+	rec := &record{
+		segment:  s.active,
+		offset:   s.active.size,
+		state:    recordChecked,
+		length:   uint32(len(payload)),
+		priority: c.priority,
+		seq:      c.seq,
+		enqueued: c.enqueued,
+		carried:  c.carried,
+		attempts: c.attempts,
+		headers:  c.headers,
+		deadline: c.deadline,
+		missed:   c.missed,
+	}
+	buffer := append(encodeHeader(rec, payload), payload...)
+	if _, err = s.active.file.WriteAt(buffer, rec.offset); err != nil {
+		return
+	}
+	s.active.size += int64(len(buffer))
+	s.active.live++
+	heap.Push(&s.index, rec)
+	return
+}
+
+//pop reads the best record back into a container
+//Note: A record that can not be read is still removed
+func (s *spill) pop() (c *container, err error) {
+	c, err = s.take(0)
+	return
+}
+
+//find returns the index of the record with a sequence number
+func (s *spill) find(seq uint64) (index int, ok bool) {
+	for index = range s.index.list {
+		if s.index.list[index].seq == seq {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+//take reads a record (by index) back into a container
+//Note: A record that can not be read is still removed; its error is a *CorruptionError and the
+//container holds what the index knew of it (no element).
+func (s *spill) take(index int) (c *container, err error) {
+	rec := heap.Remove(&s.index, index).(*record)
+	defer s.release(rec)
+	defer func() {
+		if err == nil {
+			return
+		}
+		c = rec.stub()
+		if _, ok := err.(*CorruptionError); !ok {
+			err = &CorruptionError{Report: Report{Corruptions: []Corruption{
+				{Path: rec.segment.file.Name(), Offset: rec.offset, Reason: err.Error()},
+			}}}
+		}
+	}()
+	//Read
+	payload := make([]byte, rec.length)
+	if _, err = rec.segment.file.ReadAt(payload, rec.offset+int64(rec.headerSize())); err != nil {
+		return
+	}
+	if rec.state&recordChecked != 0 && rec.sum(payload) != rec.checksum {
+		err = &CorruptionError{Report: Report{Corruptions: []Corruption{
+			{Path: rec.segment.file.Name(), Offset: rec.offset, Reason: "checksum mismatch"},
+		}}}
+		return
+	}
+	var element interface{}
+	if element, err = s.codec.Decode(payload); err != nil {
+		return
+	}
+	c = &container{
+		element:  element,
+		priority: rec.priority,
+		seq:      rec.seq,
+		enqueued: rec.enqueued,
+		carried:  rec.carried,
+		attempts: rec.attempts,
+		headers:  rec.headers,
+		deadline: rec.deadline,
+		missed:   rec.missed,
+	}
+	return
+}
+
+//release marks a record consumed and removes its segment once nothing in it is live
+func (s *spill) release(rec *record) {
+	seg := rec.segment
+	seg.live--
+	_, _ = seg.file.WriteAt([]byte{rec.state | recordConsumed}, rec.offset)
+	if seg.live > 0 {
+		return
+	}
+	//Reuse the active segment, remove the others
+	if seg == s.active {
+		if seg.file.Truncate(seg.start) == nil {
+			seg.size = seg.start
+		}
+		return
+	}
+	s.remove(seg)
+}
+
+//remove closes and deletes a segment
+func (s *spill) remove(seg *segment) {
+	seg.file.Close()
+	os.Remove(seg.file.Name())
+	delete(s.segments, seg.id)
+}
+
+//rotate starts a new active segment
+func (s *spill) rotate() (err error) {
+	id := s.nextID()
+	var file *os.File
+	if file, err = os.OpenFile(segmentPath(s.dir, id), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644); err != nil {
+		return
+	}
+	header := segmentHeader()
+	if _, err = file.WriteAt(header, 0); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return
+	}
+	s.active = &segment{id: id, file: file, start: int64(len(header)), size: int64(len(header))}
+	s.segments[id] = s.active
+	return
+}
+
+//close closes the segments (they stay on disk)
+func (s *spill) close() {
+	for _, seg := range s.segments {
+		seg.file.Close()
+	}
+	s.segments, s.active, s.index.list = nil, nil, nil
+}
+
+//encodeHeader encodes the header of a record, with the checksum of the payload if checked
+func encodeHeader(rec *record, payload []byte) (header []byte) {
+	header = make([]byte, rec.headerSize())
+	header[0] = rec.state
+	binary.BigEndian.PutUint64(header[1:], uint64(int64(rec.priority)))
+	binary.BigEndian.PutUint64(header[9:], rec.seq)
+	binary.BigEndian.PutUint32(header[17:], rec.length)
+	if rec.state&recordChecked != 0 {
+		rec.checksum = rec.sum(payload)
+		binary.BigEndian.PutUint32(header[recordHeaderSize:], rec.checksum)
+	}
+	return
+}
+
+//sum returns the checksum a record with payload should carry
+func (a *record) sum(payload []byte) uint32 {
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint64(header[1:], uint64(int64(a.priority)))
+	binary.BigEndian.PutUint64(header[9:], a.seq)
+	binary.BigEndian.PutUint32(header[17:], a.length)
+	return checksum(header[1:], payload)
+}
+
+//decodeHeader decodes the header of a record
+func decodeHeader(header []byte) (rec *record) {
+	rec = &record{
+		state:    header[0],
+		priority: int(int64(binary.BigEndian.Uint64(header[1:]))),
+		seq:      binary.BigEndian.Uint64(header[9:]),
+		length:   binary.BigEndian.Uint32(header[17:]),
+	}
+	if rec.state&recordChecked != 0 && len(header) >= recordHeaderSize+recordChecksumSize {
+		rec.checksum = binary.BigEndian.Uint32(header[recordHeaderSize:])
+	}
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Heap
+//---------------------------------------------------------------------------------------------------
+
+//records is a heap of records in queue order
+type records struct {
+	list   []*record
+	before func(a, b *container) bool //queue order (nil is priority then sequence)
+}
+
+//Len implements Length
+func (r *records) Len() int {
+	return len(r.list)
+}
+
+//Less implements Less
+func (r *records) Less(i, j int) bool {
+	if r.before == nil {
+		return r.list[i].before(&container{priority: r.list[j].priority, seq: r.list[j].seq})
+	}
+	return r.before(r.list[i].stub(), r.list[j].stub())
+}
+
+//Swap implements Swap
+func (r *records) Swap(i, j int) {
+	r.list[i], r.list[j] = r.list[j], r.list[i]
+}
+
+//Push implements Push
+func (r *records) Push(x interface{}) {
+	r.list = append(r.list, x.(*record))
+}
+
+//Pop implements Pop
+func (r *records) Pop() interface{} {
+	old := r.list
+	rec := old[len(old)-1]
+	old[len(old)-1] = nil
+	r.list = old[:len(old)-1]
+	return rec
+}
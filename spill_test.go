@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//tempDir creates a temporary directory for a test
+func tempDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "go-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Spill
+//---------------------------------------------------------------------------------------------------
+
+//TestSpill will test spilling to disk and paging back in
+func TestSpill(t *testing.T) {
+	const name string = "Spill"
+	cases := map[string]struct {
+		iSize        int
+		iSegmentSize int64
+		iElements    []interface{}
+		iPriorities  []int
+		oSpilled     int
+		oLength      int
+		oElements    []interface{}
+		oPriorities  []int
+	}{
+		"Under_Size": {
+			iSize:       3,
+			iElements:   []interface{}{1, 2},
+			iPriorities: []int{0, 1},
+			oSpilled:    0,
+			oLength:     2,
+			oElements:   []interface{}{2, 1},
+			oPriorities: []int{1, 0},
+		},
+		"Over_Size": {
+			iSize:       2,
+			iElements:   []interface{}{1, 2, 3, 4, 5},
+			iPriorities: []int{0, 0, 5, 0, 10},
+			oSpilled:    3,
+			oLength:     5,
+			oElements:   []interface{}{5, 3, 1, 2, 4},
+			oPriorities: []int{10, 5, 0, 0, 0},
+		},
+		"Over_Size_Small_Segments": {
+			iSize:        1,
+			iSegmentSize: 1,
+			iElements:    []interface{}{"a", "b", "c", "d"},
+			iPriorities:  []int{1, 2, 1, 2},
+			oSpilled:     3,
+			oLength:      4,
+			oElements:    []interface{}{"b", "d", "a", "c"},
+			oPriorities:  []int{2, 2, 1, 1},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+		//Create Queue
+		testQueue := NewQueue(c.iSize, true)
+		defer testQueue.Close()
+		if err := testQueue.Spill(SpillConfig{Dir: dir, SegmentSize: c.iSegmentSize}); err != nil {
+			t.Fatal(err)
+		}
+		//Enqueue
+		for index, element := range c.iElements {
+			if overflow := testQueue.EnqueuePriority(element, c.iPriorities[index]); overflow {
+				t.Fatalf(fatalOverflow, msg)
+			}
+		}
+		//Assert
+		assert.Equal(t, c.oSpilled, testQueue.GetSpilled(), fmt.Sprintf("%s :Spilled", msg))
+		assert.Equal(t, c.oLength, testQueue.GetLength(), fmt.Sprintf("%s :Length", msg))
+		//Dequeue
+		var elements []interface{}
+		var priorities []int
+		for range c.iElements {
+			element, priority, underflow := testQueue.DequeuePriority()
+			if underflow {
+				t.Fatalf(fatalUnderflow, msg)
+			}
+			elements = append(elements, element)
+			priorities = append(priorities, priority)
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oPriorities, priorities, fmt.Sprintf("%s :Priorities", msg))
+		assert.Equal(t, 0, testQueue.GetSpilled(), fmt.Sprintf("%s :Drained", msg))
+	}
+}
+
+//TestSpillReopen will test picking up what a closed queue left on disk
+func TestSpillReopen(t *testing.T) {
+	const name string = "SpillReopen"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	//Fill a queue and close it
+	first := NewQueue(1, true)
+	if err := first.Spill(SpillConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	for index, element := range []interface{}{1, 2, 3} {
+		if overflow := first.EnqueuePriority(element, index); overflow {
+			t.Fatalf(fatalOverflow, name)
+		}
+	}
+	first.Dequeue()
+	first.Close() //Drops what is in memory (2)
+	//Reopen with a new queue
+	second := NewQueue(1, true)
+	defer second.Close()
+	second.Enqueue(0)
+	assert.Equal(t, ErrSpillNotEmpty, second.Spill(SpillConfig{Dir: dir}), fmt.Sprintf("%s :NotEmpty", name))
+	second.Dequeue()
+	if err := second.Spill(SpillConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrSpillEnabled, second.Spill(SpillConfig{Dir: dir}), fmt.Sprintf("%s :Enabled", name))
+	second.Enqueue(4)
+	//Assert
+	elements, _ := second.Flush()
+	assert.Equal(t, []interface{}{1, 4}, elements, fmt.Sprintf("%s :Elements", name))
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"time"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//DefaultLatencyBounds are the upper bounds of the enqueue to dequeue latency histogram
+var DefaultLatencyBounds = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Statistics provides methods of getting statistics about a queue
+type Statistics interface {
+	//Stats returns a snapshot of the statistics
+	Stats() (stats Stats)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Stats
+//---------------------------------------------------------------------------------------------------
+
+//Stats is a snapshot of the statistics of a queue
+type Stats struct {
+	Size       int           //max size of the queue
+	Length     int           //current length (memory and disk)
+	Spilled    int           //current length on disk
+	Enqueued   uint64        //elements enqueued
+	Dequeued   uint64        //elements dequeued
+	Overflows  uint64        //enqueues refused as full
+	Underflows uint64        //dequeues attempted while empty
+	Flushed    uint64        //elements removed by Flush or Resize
+	Expired    uint64        //elements dropped for missing their deadline (MissDrop)
+	Missed     uint64        //elements past their deadline (delivered late, demoted or dropped)
+	Evicted    uint64        //elements written to disk for lack of room in memory
+	Corrupted  uint64        //spilled elements dropped as they could not be read back
+	Depth      map[int]int   //current length by priority
+	OldestAge  time.Duration //how long the oldest element has waited
+	Latency    Histogram     //time from enqueue to dequeue
+}
+
+//Histogram is a cumulative distribution of durations
+type Histogram struct {
+	Bounds []time.Duration //upper bounds (inclusive) of the buckets
+	Counts []uint64        //observations per bucket, with an extra bucket for above the last bound
+	Count  uint64          //observations
+	Sum    time.Duration   //sum of the observations
+}
+
+//observe adds an observation
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(h.Bounds)+1)
+	}
+	index := len(h.Bounds)
+	for i, bound := range h.Bounds {
+		if d <= bound {
+			index = i
+			break
+		}
+	}
+	h.Counts[index]++
+	h.Count++
+	h.Sum += d
+}
+
+//copy returns a deep copy
+func (h *Histogram) copy() (c Histogram) {
+	c = Histogram{Count: h.Count, Sum: h.Sum}
+	c.Bounds = append([]time.Duration(nil), h.Bounds...)
+	c.Counts = make([]uint64, len(h.Bounds)+1)
+	copy(c.Counts, h.Counts)
+	return
+}
+
+//counters are the running statistics of a queue
+type counters struct {
+	enqueued   uint64
+	dequeued   uint64
+	overflows  uint64
+	underflows uint64
+	flushed    uint64
+	expired    uint64
+	missed     uint64
+	evicted    uint64
+	corrupted  uint64
+	latency    Histogram
+}
+
+//---------------------------------------------------------------------------------------------------
+// Statistics Implementation
+//---------------------------------------------------------------------------------------------------
+
+//Stats returns a snapshot of the statistics
+func (q *queue) Stats() (stats Stats) {
+	q.Lock()
+	defer q.Unlock()
+	now := time.Now()
+	stats = Stats{
+		Size:       q.size,
+		Enqueued:   q.counters.enqueued,
+		Dequeued:   q.counters.dequeued,
+		Overflows:  q.counters.overflows,
+		Underflows: q.counters.underflows,
+		Flushed:    q.counters.flushed,
+		Expired:    q.counters.expired,
+		Missed:     q.counters.missed,
+		Evicted:    q.counters.evicted,
+		Corrupted:  q.counters.corrupted,
+		Depth:      make(map[int]int),
+		Latency:    q.counters.latency.copy(),
+	}
+	//Walk memory and disk
+	var oldest time.Time
+	track := func(priority int, enqueued time.Time) {
+		stats.Length++
+		stats.Depth[priority]++
+		if oldest.IsZero() || enqueued.Before(oldest) {
+			oldest = enqueued
+		}
+	}
+	for _, container := range q.containers {
+		track(container.priority, container.enqueued)
+	}
+	if q.spill != nil {
+		stats.Spilled = q.spill.Len()
+		for _, rec := range q.spill.index.list {
+			track(rec.priority, rec.enqueued)
+		}
+	}
+	if !oldest.IsZero() {
+		stats.OldestAge = now.Sub(oldest)
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Stats
+//---------------------------------------------------------------------------------------------------
+
+//TestStats will test the statistics
+func TestStats(t *testing.T) {
+	const name string = "Stats"
+	cases := map[string]struct {
+		iSize       int
+		iElements   []interface{}
+		iPriorities []int
+		iDequeues   int
+		iFlush      bool
+		oStats      Stats
+	}{
+		"Empty": {
+			iSize:  2,
+			oStats: Stats{Size: 2, Depth: map[int]int{}},
+		},
+		"Overflow_Underflow": {
+			iSize:       2,
+			iElements:   []interface{}{1, 2, 3},
+			iPriorities: []int{0, 1, 1},
+			iDequeues:   3,
+			oStats: Stats{
+				Size:       2,
+				Enqueued:   2,
+				Dequeued:   2,
+				Overflows:  1,
+				Underflows: 1,
+				Depth:      map[int]int{},
+			},
+		},
+		"Depth": {
+			iSize:       4,
+			iElements:   []interface{}{1, 2, 3, 4},
+			iPriorities: []int{0, 1, 1, 5},
+			iDequeues:   1,
+			oStats: Stats{
+				Size:     4,
+				Length:   3,
+				Enqueued: 4,
+				Dequeued: 1,
+				Depth:    map[int]int{0: 1, 1: 2},
+			},
+		},
+		"Flush": {
+			iSize:       4,
+			iElements:   []interface{}{1, 2},
+			iPriorities: []int{0, 0},
+			iFlush:      true,
+			oStats: Stats{
+				Size:     4,
+				Enqueued: 2,
+				Flushed:  2,
+				Depth:    map[int]int{},
+			},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(c.iSize, true)
+		defer testQueue.Close()
+		//Enqueue, dequeue and flush
+		for index, element := range c.iElements {
+			testQueue.EnqueuePriority(element, c.iPriorities[index])
+		}
+		for i := 0; i < c.iDequeues; i++ {
+			testQueue.Dequeue()
+		}
+		if c.iFlush {
+			testQueue.Flush()
+		}
+		//Get Stats
+		stats := testQueue.Stats()
+		//Assert
+		assert.Equal(t, c.oStats.Size, stats.Size, fmt.Sprintf("%s :Size", msg))
+		assert.Equal(t, c.oStats.Length, stats.Length, fmt.Sprintf("%s :Length", msg))
+		assert.Equal(t, c.oStats.Enqueued, stats.Enqueued, fmt.Sprintf("%s :Enqueued", msg))
+		assert.Equal(t, c.oStats.Dequeued, stats.Dequeued, fmt.Sprintf("%s :Dequeued", msg))
+		assert.Equal(t, c.oStats.Overflows, stats.Overflows, fmt.Sprintf("%s :Overflows", msg))
+		assert.Equal(t, c.oStats.Underflows, stats.Underflows, fmt.Sprintf("%s :Underflows", msg))
+		assert.Equal(t, c.oStats.Flushed, stats.Flushed, fmt.Sprintf("%s :Flushed", msg))
+		assert.Equal(t, c.oStats.Depth, stats.Depth, fmt.Sprintf("%s :Depth", msg))
+		assert.Equal(t, c.oStats.Dequeued, stats.Latency.Count, fmt.Sprintf("%s :Latency", msg))
+		assert.Equal(t, c.oStats.Length > 0, stats.OldestAge > 0, fmt.Sprintf("%s :OldestAge", msg))
+	}
+}
+
+//TestStatsSpill will test the statistics of a spilling queue
+func TestStatsSpill(t *testing.T) {
+	const name string = "StatsSpill"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	testQueue := NewQueue(1, true)
+	defer testQueue.Close()
+	if err := testQueue.Spill(SpillConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	for index, element := range []interface{}{1, 2, 3} {
+		testQueue.EnqueuePriority(element, index)
+	}
+	time.Sleep(2 * time.Millisecond)
+	testQueue.Dequeue()
+	stats := testQueue.Stats()
+	//Assert
+	assert.Equal(t, 2, stats.Length, fmt.Sprintf("%s :Length", name))
+	assert.Equal(t, 1, stats.Spilled, fmt.Sprintf("%s :Spilled", name))
+	assert.Equal(t, uint64(2), stats.Evicted, fmt.Sprintf("%s :Evicted", name))
+	assert.Equal(t, map[int]int{0: 1, 1: 1}, stats.Depth, fmt.Sprintf("%s :Depth", name))
+	assert.True(t, stats.OldestAge >= 2*time.Millisecond, fmt.Sprintf("%s :OldestAge", name))
+	assert.Equal(t, uint64(1), stats.Latency.Count, fmt.Sprintf("%s :Latency", name))
+	assert.Equal(t, uint64(0), stats.Latency.Counts[0], fmt.Sprintf("%s :Latency", name))
+}
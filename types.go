@@ -1,5 +1,9 @@
 package queue
 
+import (
+	"time"
+)
+
 //---------------------------------------------------------------------------------------------
 // Generics
 //---------------------------------------------------------------------------------------------
@@ -20,4 +24,19 @@ const (
 type container struct {
 	element  interface{}
 	priority int
+	seq      uint64            //sequence number used to keep FIFO order between equal priorities
+	enqueued time.Time         //when the element was enqueued
+	carried  *carried          //values from the enqueue context (nil if none)
+	attempts int               //times dequeued
+	headers  map[string]string //user headers
+	deadline time.Time         //when it must be dequeued by (zero if none)
+	missed   bool              //past its deadline
+}
+
+//before reports if container a should be dequeued before container b
+func (a *container) before(b *container) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
 }
@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//RecoveryMode decides what happens to damaged records
+type RecoveryMode int
+
+const (
+	//RecoverFail refuses to open damaged files (returns a *CorruptionError)
+	RecoverFail RecoveryMode = iota
+	//RecoverSkip drops damaged records and keeps going where possible
+	RecoverSkip
+	//RecoverTruncate cuts a segment off at its first damaged record
+	RecoverTruncate
+)
+
+//castagnoli is the table for the record checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+//checksum returns the checksum of a header and payload
+func checksum(header, payload []byte) uint32 {
+	return crc32.Update(crc32.Checksum(header, castagnoli), castagnoli, payload)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Report
+//---------------------------------------------------------------------------------------------------
+
+//Corruption is a single problem found in a file
+type Corruption struct {
+	Path   string //the damaged file
+	Offset int64  //where the damage starts
+	Reason string //what is wrong
+}
+
+//Report is what was found checking (or recovering) persisted files
+type Report struct {
+	Segments    int          //files checked
+	Records     int          //records (or used slots) checked
+	Live        int          //intact records still in the queue
+	Dropped     int          //damaged records skipped
+	Truncated   int64        //bytes cut off
+	Corruptions []Corruption //the problems found
+}
+
+//Healthy reports if nothing was found
+func (r Report) Healthy() bool {
+	return len(r.Corruptions) == 0
+}
+
+//CorruptionError is returned when damaged files are found and not recovered
+type CorruptionError struct {
+	Report Report
+}
+
+//Error implements error
+func (e *CorruptionError) Error() string {
+	first := e.Report.Corruptions[0]
+	return fmt.Sprintf("queue: %d corruption(s), first in %s at %d: %s", len(e.Report.Corruptions), first.Path, first.Offset, first.Reason)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Verify
+//---------------------------------------------------------------------------------------------------
+
+//Verify checks persisted queue files without changing them
+//The path is either a spill directory, whose segments are checked record by record and against
+//each other, or a shared queue file, whose slots are checked against each other and its header.
+//The error is only set if the files could not be read.
+func Verify(path string) (report Report, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	}
+	if info.IsDir() {
+		err = verifySpill(path, &report)
+		return
+	}
+	err = verifyShared(path, &report)
+	return
+}
+
+//verifySpill checks the segments of a spill directory
+func verifySpill(dir string, report *Report) (err error) {
+	var ids []uint64
+	if ids, err = listSegments(dir); err != nil {
+		return
+	}
+	//Every sequence number must be unique across the segments
+	seen := make(map[uint64]string)
+	for _, id := range ids {
+		path := segmentPath(dir, id)
+		var file *os.File
+		if file, err = os.Open(path); err != nil {
+			return
+		}
+		var start int64
+		if _, start, err = readSegmentHeader(file); err == ErrFormatVersion {
+			report.Segments++
+			report.Corruptions = append(report.Corruptions, Corruption{Path: path, Reason: "unsupported format version"})
+			file.Close()
+			err = nil
+			continue
+		} else if err != nil {
+			file.Close()
+			return
+		}
+		_, _, err = scanSegment(file, start, RecoverSkip, false, report, func(rec *record) {
+			if other, ok := seen[rec.seq]; ok {
+				report.Corruptions = append(report.Corruptions, Corruption{
+					Path:   path,
+					Offset: rec.offset,
+					Reason: fmt.Sprintf("sequence %d also in %s", rec.seq, filepath.Base(other)),
+				})
+				return
+			}
+			seen[rec.seq] = path
+		})
+		file.Close()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+//verifyShared checks a shared queue file
+func verifyShared(path string, report *Report) (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	report.Segments++
+	//Check the header
+	if len(data) < sharedHeaderSize || string(data[:4]) != sharedMagic {
+		report.Corruptions = append(report.Corruptions, Corruption{Path: path, Reason: "not a shared queue file"})
+		return
+	}
+	if sharedVersion(data) > FormatVersion {
+		report.Corruptions = append(report.Corruptions, Corruption{Path: path, Reason: "unsupported format version"})
+		return
+	}
+	size, slotSize, slotHeader := decodeSharedHeader(data)
+	if len(data) != sharedHeaderSize+size*(slotHeader+slotSize) {
+		report.Corruptions = append(report.Corruptions, Corruption{Path: path, Reason: "file size does not match header"})
+		return
+	}
+	scanShared(data, path, RecoverSkip, false, report)
+	return
+}
+
+//scanShared checks the slots of a (mapped) shared queue file
+//With repair, damaged slots are freed unless mode is RecoverFail. The header is only checked
+//against the slots if it was left clean.
+func scanShared(data []byte, path string, mode RecoveryMode, repair bool, report *Report) (corruptions int) {
+	size, slotSize, slotHeader := decodeSharedHeader(data)
+	corrupt := func(offset int64, reason string) {
+		corruptions++
+		report.Corruptions = append(report.Corruptions, Corruption{Path: path, Offset: offset, Reason: reason})
+	}
+	seen := make(map[uint64]bool)
+	var length uint32
+	for index := 0; index < size; index++ {
+		offset := sharedHeaderSize + index*(slotHeader+slotSize)
+		slot := data[offset : offset+slotHeader+slotSize]
+		//Check the state
+		if slot[0] == slotFree {
+			continue
+		}
+		report.Records++
+		reason := ""
+		if slot[0] != slotUsed {
+			reason = "invalid slot state"
+		} else if int(binary.BigEndian.Uint32(slot[4:])) > slotSize {
+			reason = "invalid slot length"
+		} else if slotHeader > slotHeaderSize {
+			payload := slot[slotHeader : slotHeader+int(binary.BigEndian.Uint32(slot[4:]))]
+			if checksum(slot[4:slotHeaderSize], payload) != binary.BigEndian.Uint32(slot[slotHeaderSize:]) {
+				reason = "checksum mismatch"
+			}
+		}
+		if reason != "" {
+			corrupt(int64(offset), reason)
+			if repair && mode != RecoverFail {
+				slot[0] = slotFree
+				report.Dropped++
+			}
+			continue
+		}
+		//Check the sequence
+		seq := binary.BigEndian.Uint64(slot[16:])
+		if seen[seq] {
+			corrupt(int64(offset), fmt.Sprintf("duplicate sequence %d", seq))
+		}
+		seen[seq] = true
+		report.Live++
+		length++
+	}
+	//Check the header against the slots
+	if binary.BigEndian.Uint32(data[12:]) == sharedClean && corruptions == 0 && binary.BigEndian.Uint32(data[24:]) != length {
+		corrupt(24, "length does not match slots")
+	}
+	return
+}
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//corruptFile flips a byte in a file
+func corruptFile(t *testing.T, path string, offset int64) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	b := make([]byte, 1)
+	if _, err = file.ReadAt(b, offset); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xFF
+	if _, err = file.WriteAt(b, offset); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//---------------------------------------------------------------------------------------------------
+// Verify
+//---------------------------------------------------------------------------------------------------
+
+//TestVerifySpill will test checking and recovering a damaged spill
+func TestVerifySpill(t *testing.T) {
+	const name string = "VerifySpill"
+	cases := map[string]struct {
+		iRecovery  RecoveryMode
+		oError     bool
+		oElements  []interface{}
+		oDropped   int
+		oTruncated bool
+	}{
+		"Fail": {
+			iRecovery: RecoverFail,
+			oError:    true,
+		},
+		"Skip": {
+			iRecovery: RecoverSkip,
+			oElements: []interface{}{3},
+			oDropped:  1,
+		},
+		"Truncate": {
+			iRecovery:  RecoverTruncate,
+			oElements:  nil,
+			oTruncated: true,
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+		//Spill 2 and 3 then damage the payload of 2
+		first := NewQueue(1, true)
+		if err := first.Spill(SpillConfig{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+		for _, element := range []interface{}{1, 2, 3} {
+			first.Enqueue(element)
+		}
+		first.Close()
+		corruptFile(t, segmentPath(dir, 0), int64(segmentHeaderSize+recordHeaderSize+recordChecksumSize))
+		//Verify
+		report, err := Verify(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Assert
+		assert.False(t, report.Healthy(), fmt.Sprintf("%s :Healthy", msg))
+		assert.Equal(t, 1, report.Live, fmt.Sprintf("%s :Live", msg))
+		assert.Equal(t, "checksum mismatch", report.Corruptions[0].Reason, fmt.Sprintf("%s :Reason", msg))
+		//Recover
+		var recovered Report
+		second := NewQueue(1, true)
+		defer second.Close()
+		err = second.Spill(SpillConfig{Dir: dir, Recovery: c.iRecovery, OnRecover: func(r Report) { recovered = r }})
+		//Assert
+		if c.oError {
+			_, ok := err.(*CorruptionError)
+			assert.True(t, ok, fmt.Sprintf("%s :Error", msg))
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		elements, _ := second.Flush()
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, c.oDropped, recovered.Dropped, fmt.Sprintf("%s :Dropped", msg))
+		assert.Equal(t, c.oTruncated, recovered.Truncated > 0, fmt.Sprintf("%s :Truncated", msg))
+		second.Close()
+		report, _ = Verify(dir)
+		assert.True(t, report.Healthy(), fmt.Sprintf("%s :Recovered", msg))
+	}
+}
+
+//TestVerifyShared will test checking and recovering a damaged shared queue file
+func TestVerifyShared(t *testing.T) {
+	const name string = "VerifyShared"
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "shared")
+	q, err := NewSharedQueue(path, SharedConfig{Size: 2})
+	if err == ErrSharedUnsupported {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Close()
+	//Damage the payload of the first slot
+	corruptFile(t, path, int64(sharedHeaderSize+slotHeaderSize+slotChecksumSize))
+	report, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Assert
+	assert.Equal(t, 2, report.Records, fmt.Sprintf("%s :Records", name))
+	assert.Equal(t, 1, report.Live, fmt.Sprintf("%s :Live", name))
+	assert.Equal(t, "checksum mismatch", report.Corruptions[0].Reason, fmt.Sprintf("%s :Reason", name))
+	//Refuse then recover
+	_, err = NewSharedQueue(path, SharedConfig{})
+	_, ok := err.(*CorruptionError)
+	assert.True(t, ok, fmt.Sprintf("%s :Error", name))
+	q, err = NewSharedQueue(path, SharedConfig{Recovery: RecoverSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	elements, _ := q.Flush()
+	assert.Equal(t, []interface{}{2}, elements, fmt.Sprintf("%s :Elements", name))
+}
+
+//TestSpillCorrupted will test a record damaged while spilled is reported when paged back in
+func TestSpillCorrupted(t *testing.T) {
+	const name string = "SpillCorrupted"
+	cases := map[string]struct {
+		iRemove   bool //remove the damaged one by handle rather than dequeue
+		oElements []interface{}
+	}{
+		"Dequeue": {
+			oElements: []interface{}{1, 3},
+		},
+		"Remove": {
+			iRemove:   true,
+			oElements: []interface{}{1, 3},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+		//Spill 2 and 3 then damage the payload of 2
+		var reports []Report
+		testQueue := NewQueue(1, true)
+		if err := testQueue.Spill(SpillConfig{Dir: dir, OnRecover: func(r Report) { reports = append(reports, r) }}); err != nil {
+			t.Fatal(err)
+		}
+		changes, unsubscribe := testQueue.Subscribe(10, false)
+		for _, element := range []interface{}{1, 2, 3} {
+			testQueue.Enqueue(element)
+		}
+		corruptFile(t, segmentPath(dir, 0), int64(segmentHeaderSize+recordHeaderSize+recordChecksumSize))
+		if c.iRemove {
+			element, _, found := testQueue.Remove(1)
+			assert.True(t, found, fmt.Sprintf("%s :Found", msg))
+			assert.Nil(t, element, fmt.Sprintf("%s :Removed", msg))
+		}
+		//Dequeue
+		var elements []interface{}
+		for {
+			element, underflow := testQueue.Dequeue()
+			if underflow {
+				break
+			}
+			elements = append(elements, element)
+		}
+		unsubscribe()
+		corrupted := 0
+		for change := range changes {
+			if change.Kind == Corrupted {
+				assert.Equal(t, uint64(1), change.Handle, fmt.Sprintf("%s :Handle", msg))
+				corrupted++
+			}
+		}
+		//Assert
+		assert.Equal(t, c.oElements, elements, fmt.Sprintf("%s :Elements", msg))
+		assert.Equal(t, uint64(1), testQueue.Stats().Corrupted, fmt.Sprintf("%s :Stats", msg))
+		assert.Equal(t, 1, corrupted, fmt.Sprintf("%s :Changes", msg))
+		if assert.Len(t, reports, 1, fmt.Sprintf("%s :Reports", msg)) {
+			assert.Equal(t, 1, reports[0].Dropped, fmt.Sprintf("%s :Dropped", msg))
+			assert.Equal(t, "checksum mismatch", reports[0].Corruptions[0].Reason, fmt.Sprintf("%s :Reason", msg))
+		}
+		testQueue.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"errors"
+)
+
+var (
+	//ErrWatermarks is returned when the watermarks are not 0 <= low < high <= 1
+	ErrWatermarks = errors.New("queue: invalid watermarks")
+)
+
+//---------------------------------------------------------------------------------------------------
+// Generics
+//---------------------------------------------------------------------------------------------------
+
+//Watermark is a crossing of a watermark
+type Watermark struct {
+	High   bool //true when the high watermark was reached, false when back down to the low one
+	Length int  //length of the queue at the crossing
+	Size   int  //size of the queue at the crossing
+}
+
+//---------------------------------------------------------------------------------------------------
+// Interface
+//---------------------------------------------------------------------------------------------------
+
+//Watermarks provides methods of backpressure
+type Watermarks interface {
+	//SetWatermarks will set the high and low watermarks (fractions of size) and return the crossings
+	SetWatermarks(high, low float64) (crossings <-chan Watermark, err error)
+	//GetHigh returns true if the high watermark was reached and the low one not yet since
+	GetHigh() (high bool)
+}
+
+//---------------------------------------------------------------------------------------------------
+// Watermarks Implementation
+//---------------------------------------------------------------------------------------------------
+
+//SetWatermarks will set the high and low watermarks (fractions of size) and return the crossings
+//The high crossing is reported once length reaches high*size, then nothing more until length is
+//back down to low*size (hysteresis). The channel holds the latest crossing only, so a slow reader
+//sees the current state rather than a backlog. Setting again replaces (and closes) the channel.
+func (q *queue) SetWatermarks(high, low float64) (crossings <-chan Watermark, err error) {
+	if low < 0 || high > 1 || low >= high {
+		err = ErrWatermarks
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.closeWatermarks()
+	q.watermarks = &watermarks{high: high, low: low, crossings: make(chan Watermark, 1)}
+	crossings = q.watermarks.crossings
+	//Report where it already stands
+	q.watermark()
+	return
+}
+
+//GetHigh returns true if the high watermark was reached and the low one not yet since
+func (q *queue) GetHigh() (high bool) {
+	q.Lock()
+	defer q.Unlock()
+	high = q.watermarks != nil && q.watermarks.reached
+	return
+}
+
+//---------------------------------------------------------------------------------------------------
+// Hidden
+//---------------------------------------------------------------------------------------------------
+
+//watermarks is the state of the watermarks
+type watermarks struct {
+	high, low float64
+	reached   bool
+	crossings chan Watermark
+}
+
+//watermark will report a crossing if the length has crossed a watermark (locked)
+func (q *queue) watermark() {
+	w := q.watermarks
+	if w == nil {
+		return
+	}
+	length := q.length()
+	switch {
+	case !w.reached && float64(length) >= w.high*float64(q.size):
+		w.reached = true
+	case w.reached && float64(length) <= w.low*float64(q.size):
+		w.reached = false
+	default:
+		return
+	}
+	crossing := Watermark{High: w.reached, Length: length, Size: q.size}
+	//Latest wins
+	select {
+	case <-w.crossings:
+	default:
+	}
+	w.crossings <- crossing
+}
+
+//closeWatermarks will close the crossings (locked)
+func (q *queue) closeWatermarks() {
+	if q.watermarks != nil {
+		close(q.watermarks.crossings)
+		q.watermarks = nil
+	}
+}
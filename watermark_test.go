@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//---------------------------------------------------------------------------------------------------
+// Watermarks
+//---------------------------------------------------------------------------------------------------
+
+//TestSetWatermarks will test the watermark crossings
+func TestSetWatermarks(t *testing.T) {
+	const name string = "SetWatermarks"
+	cases := map[string]struct {
+		iHigh      float64
+		iLow       float64
+		iPrefill   int
+		iLengths   []int //lengths to walk the queue through
+		oErr       error
+		oCrossings []Watermark
+	}{
+		"Invalid": {
+			iHigh: 0.2,
+			iLow:  0.5,
+			oErr:  ErrWatermarks,
+		},
+		"Hysteresis": {
+			iHigh:    0.8,
+			iLow:     0.2,
+			iLengths: []int{8, 7, 9, 3, 8, 2, 1, 8},
+			oCrossings: []Watermark{
+				{High: true, Length: 8, Size: 10},
+				{High: false, Length: 2, Size: 10},
+				{High: true, Length: 8, Size: 10},
+			},
+		},
+		"Already_High": {
+			iHigh:    0.5,
+			iLow:     0.1,
+			iPrefill: 6,
+			iLengths: []int{1},
+			oCrossings: []Watermark{
+				{High: true, Length: 6, Size: 10},
+				{High: false, Length: 1, Size: 10},
+			},
+		},
+	}
+
+	//Test cases
+	for cDesc, c := range cases {
+		//Get the assert message base
+		msg := assertMsg(name, cDesc)
+		//Create Queue
+		testQueue := NewQueue(10, true)
+		for i := 0; i < c.iPrefill; i++ {
+			testQueue.Enqueue(i)
+		}
+		crossings, err := testQueue.SetWatermarks(c.iHigh, c.iLow)
+		assert.Equal(t, c.oErr, err, fmt.Sprintf("%s :Error", msg))
+		if err != nil {
+			testQueue.Close()
+			continue
+		}
+		//Walk the lengths, reading as we go
+		var got []Watermark
+		read := func() {
+			select {
+			case crossing := <-crossings:
+				got = append(got, crossing)
+			default:
+			}
+		}
+		read()
+		for _, length := range c.iLengths {
+			for testQueue.GetLength() < length {
+				testQueue.Enqueue(0)
+			}
+			for testQueue.GetLength() > length {
+				testQueue.Dequeue()
+			}
+			read()
+		}
+		//Assert
+		assert.Equal(t, c.oCrossings, got, fmt.Sprintf("%s :Crossings", msg))
+		assert.Equal(t, c.oCrossings[len(c.oCrossings)-1].High, testQueue.GetHigh(), fmt.Sprintf("%s :High", msg))
+		testQueue.Close()
+		_, open := <-crossings
+		assert.False(t, open, fmt.Sprintf("%s :Open", msg))
+	}
+}
+
+//TestWatermarksLatest will test a slow reader seeing the latest crossing
+func TestWatermarksLatest(t *testing.T) {
+	const name string = "WatermarksLatest"
+	//Create Queue
+	testQueue := NewQueue(4, true)
+	defer testQueue.Close()
+	crossings, _ := testQueue.SetWatermarks(0.5, 0)
+	testQueue.Enqueue(1)
+	testQueue.Enqueue(2)
+	testQueue.Flush()
+	//Assert
+	assert.Equal(t, Watermark{High: false, Length: 0, Size: 4}, <-crossings, fmt.Sprintf("%s :Crossing", name))
+	assert.Equal(t, 0, len(crossings), fmt.Sprintf("%s :Backlog", name))
+}